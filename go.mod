@@ -3,47 +3,68 @@ module github.com/autopticio/instance
 go 1.23.1
 
 require (
+	github.com/aws/aws-sdk-go-v2/service/apigatewayv2 v1.24.3
+	github.com/aws/aws-sdk-go-v2/service/bedrock v1.22.0
+	github.com/aws/aws-sdk-go-v2/service/cloudformation v1.55.4
 	github.com/aws/aws-sdk-go-v2/service/cloudfront v1.40.3
+	github.com/aws/aws-sdk-go-v2/service/cloudtrail v1.44.3
 	github.com/aws/aws-sdk-go-v2/service/cloudwatch v1.42.3
+	github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs v1.43.0
+	github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider v1.46.3
+	github.com/aws/aws-sdk-go-v2/service/ecs v1.49.0
+	github.com/aws/aws-sdk-go-v2/service/efs v1.33.3
+	github.com/aws/aws-sdk-go-v2/service/eks v1.51.1
+	github.com/aws/aws-sdk-go-v2/service/elasticloadbalancing v1.28.3
 	github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2 v1.40.1
+	github.com/aws/aws-sdk-go-v2/service/emr v1.46.1
 	github.com/aws/aws-sdk-go-v2/service/globalaccelerator v1.29.3
+	github.com/aws/aws-sdk-go-v2/service/glue v1.100.3
+	github.com/aws/aws-sdk-go-v2/service/kinesis v1.32.3
+	github.com/aws/aws-sdk-go-v2/service/kms v1.37.3
+	github.com/aws/aws-sdk-go-v2/service/redshift v1.51.0
 	github.com/aws/aws-sdk-go-v2/service/route53 v1.45.3
+	github.com/aws/aws-sdk-go-v2/service/sagemaker v1.165.0
+	github.com/aws/aws-sdk-go-v2/service/secretsmanager v1.34.3
+	github.com/aws/aws-sdk-go-v2/service/sesv2 v1.37.1
+	github.com/aws/aws-sdk-go-v2/service/sfn v1.33.3
+	github.com/aws/aws-sdk-go-v2/service/sns v1.33.3
+	github.com/aws/aws-sdk-go-v2/service/sqs v1.36.3
 	github.com/spf13/cobra v1.8.1
+	github.com/stretchr/testify v1.9.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
-	github.com/stretchr/testify v1.9.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
 
 require (
-	github.com/aws/aws-sdk-go-v2 v1.32.3 // indirect
+	github.com/aws/aws-sdk-go-v2 v1.32.3
 	github.com/aws/aws-sdk-go-v2/aws/protocol/eventstream v1.6.6 // indirect
-	github.com/aws/aws-sdk-go-v2/config v1.28.1 // indirect
-	github.com/aws/aws-sdk-go-v2/credentials v1.17.42 // indirect
+	github.com/aws/aws-sdk-go-v2/config v1.28.1
+	github.com/aws/aws-sdk-go-v2/credentials v1.17.42
 	github.com/aws/aws-sdk-go-v2/feature/ec2/imds v1.16.18 // indirect
 	github.com/aws/aws-sdk-go-v2/internal/configsources v1.3.22 // indirect
 	github.com/aws/aws-sdk-go-v2/internal/endpoints/v2 v2.6.22 // indirect
 	github.com/aws/aws-sdk-go-v2/internal/ini v1.8.1 // indirect
 	github.com/aws/aws-sdk-go-v2/internal/v4a v1.3.22 // indirect
-	github.com/aws/aws-sdk-go-v2/service/apigateway v1.27.3 // indirect
-	github.com/aws/aws-sdk-go-v2/service/costexplorer v1.43.3 // indirect
-	github.com/aws/aws-sdk-go-v2/service/dynamodb v1.36.3 // indirect
-	github.com/aws/aws-sdk-go-v2/service/ec2 v1.186.1 // indirect
+	github.com/aws/aws-sdk-go-v2/service/apigateway v1.27.3
+	github.com/aws/aws-sdk-go-v2/service/costexplorer v1.43.3
+	github.com/aws/aws-sdk-go-v2/service/dynamodb v1.36.3
+	github.com/aws/aws-sdk-go-v2/service/ec2 v1.186.1
 	github.com/aws/aws-sdk-go-v2/service/internal/accept-encoding v1.12.0 // indirect
 	github.com/aws/aws-sdk-go-v2/service/internal/checksum v1.4.3 // indirect
 	github.com/aws/aws-sdk-go-v2/service/internal/endpoint-discovery v1.10.3 // indirect
 	github.com/aws/aws-sdk-go-v2/service/internal/presigned-url v1.12.3 // indirect
 	github.com/aws/aws-sdk-go-v2/service/internal/s3shared v1.18.3 // indirect
-	github.com/aws/aws-sdk-go-v2/service/lambda v1.64.1 // indirect
-	github.com/aws/aws-sdk-go-v2/service/rds v1.89.0 // indirect
-	github.com/aws/aws-sdk-go-v2/service/s3 v1.66.2 // indirect
+	github.com/aws/aws-sdk-go-v2/service/lambda v1.64.1
+	github.com/aws/aws-sdk-go-v2/service/rds v1.89.0
+	github.com/aws/aws-sdk-go-v2/service/s3 v1.66.2
 	github.com/aws/aws-sdk-go-v2/service/sso v1.24.3 // indirect
 	github.com/aws/aws-sdk-go-v2/service/ssooidc v1.28.3 // indirect
-	github.com/aws/aws-sdk-go-v2/service/sts v1.32.3 // indirect
-	github.com/aws/smithy-go v1.22.0 // indirect
+	github.com/aws/aws-sdk-go-v2/service/sts v1.32.3
+	github.com/aws/smithy-go v1.22.0
 	github.com/google/uuid v1.6.0
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
 	github.com/jmespath/go-jmespath v0.4.0 // indirect
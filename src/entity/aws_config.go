@@ -0,0 +1,107 @@
+package entity
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/spf13/cobra"
+)
+
+// awsConfigOptions selects the credentials, region and endpoints used for AWS API calls.
+// Empty options fall back to the default credential chain, like the AWS CLI.
+type awsConfigOptions struct {
+	Profile string
+	Region  string
+	// Endpoint URLs, either a URL for every service or service=URL for one service
+	EndpointURLs []string
+	RoleARN      string
+	ExternalID   string
+	// MFA device serial number or ARN required by the role's trust policy
+	MFASerial string
+}
+
+// addAWSConfigFlags adds the flags read by awsConfigOptionsFromFlags to a command calling AWS
+func addAWSConfigFlags(cmd *cobra.Command) {
+	cmd.Flags().String("profile", "", "AWS shared config profile, including SSO profiles after aws sso login")
+	cmd.Flags().String("region", "", "AWS region, overrides the profile and environment")
+	cmd.Flags().StringSlice("endpoint-url", nil, "Endpoint URL for every service, or service=URL for one service (e.g. s3=http://localhost:4566), repeatable")
+	cmd.Flags().String("role-arn", "", "IAM role to assume with the loaded credentials")
+	cmd.Flags().String("external-id", "", "External ID passed when assuming --role-arn")
+	cmd.Flags().String("mfa-serial", "", "MFA device to authenticate with when assuming --role-arn, the code is read from stdin")
+}
+
+func awsConfigOptionsFromFlags(cmd *cobra.Command) awsConfigOptions {
+	profile, _ := cmd.Flags().GetString("profile")
+	region, _ := cmd.Flags().GetString("region")
+	endpointURLs, _ := cmd.Flags().GetStringSlice("endpoint-url")
+	roleARN, _ := cmd.Flags().GetString("role-arn")
+	externalID, _ := cmd.Flags().GetString("external-id")
+	mfaSerial, _ := cmd.Flags().GetString("mfa-serial")
+	return awsConfigOptions{
+		Profile:      profile,
+		Region:       region,
+		EndpointURLs: endpointURLs,
+		RoleARN:      roleARN,
+		ExternalID:   externalID,
+		MFASerial:    mfaSerial,
+	}
+}
+
+// loadAWSConfig loads the AWS configuration with the profile, region, endpoints and role of the options
+func loadAWSConfig(options awsConfigOptions) (aws.Config, error) {
+	loadOptions := []func(*config.LoadOptions) error{
+		// Profiles with mfa_serial prompt for the code on stdin
+		config.WithAssumeRoleCredentialOptions(func(o *stscreds.AssumeRoleOptions) {
+			o.TokenProvider = stscreds.StdinTokenProvider
+		}),
+	}
+	if options.Profile != "" {
+		loadOptions = append(loadOptions, config.WithSharedConfigProfile(options.Profile))
+	}
+	if options.Region != "" {
+		loadOptions = append(loadOptions, config.WithRegion(options.Region))
+	}
+
+	for _, endpoint := range options.EndpointURLs {
+		service, endpointURL, ok := strings.Cut(endpoint, "=")
+		if !ok {
+			loadOptions = append(loadOptions, config.WithBaseEndpoint(endpoint))
+			continue
+		}
+		// Clients read service endpoints from the same variables the AWS CLI uses, e.g. AWS_ENDPOINT_URL_S3
+		name := "AWS_ENDPOINT_URL_" + strings.ToUpper(strings.NewReplacer(" ", "_", "-", "_").Replace(service))
+		if err := os.Setenv(name, endpointURL); err != nil {
+			return aws.Config{}, err
+		}
+	}
+
+	cfg, err := config.LoadDefaultConfig(context.TODO(), loadOptions...)
+	if err != nil {
+		return aws.Config{}, err
+	}
+
+	if options.RoleARN != "" {
+		provider := stscreds.NewAssumeRoleProvider(sts.NewFromConfig(cfg), options.RoleARN, func(o *stscreds.AssumeRoleOptions) {
+			o.RoleSessionName = "autopticli"
+			if options.ExternalID != "" {
+				o.ExternalID = aws.String(options.ExternalID)
+			}
+			if options.MFASerial != "" {
+				o.SerialNumber = aws.String(options.MFASerial)
+				o.TokenProvider = stscreds.StdinTokenProvider
+			}
+		})
+		cfg.Credentials = aws.NewCredentialsCache(provider)
+	}
+
+	if cfg.Region == "" {
+		return aws.Config{}, fmt.Errorf("no AWS region configured, use --region or a profile with a region")
+	}
+	return cfg, nil
+}
@@ -0,0 +1,38 @@
+package entity
+
+import (
+	"os"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadAWSConfig(t *testing.T) {
+	// Restored after the test, loadAWSConfig sets it for the cost-explorer=URL endpoint
+	t.Setenv("AWS_ENDPOINT_URL_COST_EXPLORER", "")
+	t.Setenv("AWS_ACCESS_KEY_ID", "test")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+
+	cfg, err := loadAWSConfig(awsConfigOptions{
+		Region:       "eu-west-1",
+		EndpointURLs: []string{"http://localhost:4566", "cost-explorer=http://localhost:4567"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "eu-west-1", cfg.Region)
+	assert.Equal(t, aws.String("http://localhost:4566"), cfg.BaseEndpoint)
+	assert.Equal(t, "http://localhost:4567", os.Getenv("AWS_ENDPOINT_URL_COST_EXPLORER"))
+
+	cfg, err = loadAWSConfig(awsConfigOptions{Region: "eu-west-1", RoleARN: "arn:aws:iam::123456789012:role/inventory"})
+	assert.NoError(t, err)
+	// Assuming the role is deferred until the first call needs credentials
+	_, ok := cfg.Credentials.(*aws.CredentialsCache)
+	assert.True(t, ok)
+
+	t.Setenv("AWS_REGION", "")
+	t.Setenv("AWS_DEFAULT_REGION", "")
+	t.Setenv("AWS_PROFILE", "")
+	t.Setenv("AWS_CONFIG_FILE", os.DevNull)
+	_, err = loadAWSConfig(awsConfigOptions{})
+	assert.Error(t, err)
+}
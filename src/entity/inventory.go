@@ -3,32 +3,38 @@ package entity
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/service/apigateway"
 	"github.com/aws/aws-sdk-go-v2/service/cloudfront"
-	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
 	"github.com/aws/aws-sdk-go-v2/service/costexplorer"
 	costTypes "github.com/aws/aws-sdk-go-v2/service/costexplorer/types"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	ec2Types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/elasticloadbalancing"
+	clbTypes "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancing/types"
 	"github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2"
 	elbTypes "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2/types"
 	"github.com/aws/aws-sdk-go-v2/service/globalaccelerator"
-	"github.com/aws/aws-sdk-go-v2/service/lambda"
 	"github.com/aws/aws-sdk-go-v2/service/rds"
-	"github.com/aws/aws-sdk-go-v2/service/route53"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/sts"
 	"github.com/spf13/cobra"
 )
 
+// DynamoDB item counts and target health change between collections
+func init() {
+	addVolatileMetadataKeys("item_count", "healthy_targets", "instances_*")
+}
+
 type ServiceMetadata struct {
 	ServiceName string                 `json:"service_name"`
 	Resources   []ResourceMetadata     `json:"resources"`
@@ -40,6 +46,12 @@ type ResourceMetadata struct {
 	MetaData   map[string]interface{} `json:"metadata"`
 }
 
+// setCloudWatchRef records the namespace and dimensions used to query a resource's CloudWatch metrics
+func setCloudWatchRef(metadata map[string]interface{}, namespace string, dimensions map[string]string) {
+	metadata["cloudwatch_namespace"] = namespace
+	metadata["cloudwatch_dimensions"] = dimensions
+}
+
 // Inventory Entity Commands
 func InventoryCommand() *cobra.Command {
 	cmd := &cobra.Command{
@@ -48,6 +60,18 @@ func InventoryCommand() *cobra.Command {
 	}
 
 	cmd.AddCommand(makeInventoryCommand())
+	cmd.AddCommand(wasteInventoryCommand())
+	cmd.AddCommand(metricsInventoryCommand())
+	cmd.AddCommand(coverageInventoryCommand())
+	cmd.AddCommand(lambdaRuntimesInventoryCommand())
+	cmd.AddCommand(danglingRecordsInventoryCommand())
+	cmd.AddCommand(exportInventoryCommand())
+	cmd.AddCommand(historyInventoryCommand())
+	cmd.AddCommand(showInventoryCommand())
+	cmd.AddCommand(timelineInventoryCommand())
+	cmd.AddCommand(serveInventoryCommand())
+	cmd.AddCommand(watchInventoryCommand())
+	cmd.AddCommand(commitmentsInventoryCommand())
 	// Additional inventory-related commands can be added here
 
 	return cmd
@@ -59,44 +83,261 @@ func makeInventoryCommand() *cobra.Command {
 		Short: "Create an inventory file",
 		Run: func(cmd *cobra.Command, args []string) {
 			out, _ := cmd.Flags().GetString("out")
+			discover, _ := cmd.Flags().GetString("discover")
+			window, _ := cmd.Flags().GetDuration("window")
+			history, _ := cmd.Flags().GetString("history")
+			source, _ := cmd.Flags().GetString("source")
+			promAddress, _ := cmd.Flags().GetString("prom-address")
+			sentryURL, _ := cmd.Flags().GetString("sentry-url")
+			sentryOrg, _ := cmd.Flags().GetString("sentry-org")
+			sentryToken, _ := cmd.Flags().GetString("sentry-token")
+			sentryEnv, _ := cmd.Flags().GetString("sentry-env")
+			errorsOut, _ := cmd.Flags().GetString("errors")
+			strict, _ := cmd.Flags().GetBool("strict")
+			stackMembership, _ := cmd.Flags().GetBool("stack-membership")
+			if discover != "cost" && discover != "cloudtrail" {
+				log.Printf("Unknown discovery source %s, use cost or cloudtrail\n", discover)
+				os.Exit(exitFailure)
+			}
+			if source != "aws" && source != "prometheus" && source != "sentry" {
+				log.Printf("Unknown inventory source %s, use aws, prometheus or sentry\n", source)
+				os.Exit(exitFailure)
+			}
+			if source == "prometheus" && promAddress == "" {
+				log.Println("The prometheus source needs --prom-address")
+				os.Exit(exitFailure)
+			}
+			if source == "sentry" {
+				var err error
+				sentryOrg, sentryToken, err = resolveSentryCredentials(sentryOrg, sentryToken, sentryEnv)
+				if err != nil {
+					log.Printf("Error reading Sentry credentials: %v\n", err)
+					os.Exit(exitFailure)
+				}
+				if sentryOrg == "" || sentryToken == "" {
+					log.Println("The sentry source needs an organization and a token, from --sentry-env, SENTRY_AUTH_TOKEN or --sentry-org and --sentry-token")
+					os.Exit(exitFailure)
+				}
+			}
 			log.Printf("Creating inventory at %s\n", out)
-			makeInventory(out)
+			code := makeInventory(out, inventoryOptions{
+				Source:          source,
+				Discover:        discover,
+				Window:          window,
+				History:         history,
+				PromAddress:     promAddress,
+				SentryURL:       sentryURL,
+				SentryOrg:       sentryOrg,
+				SentryToken:     sentryToken,
+				AWS:             awsConfigOptionsFromFlags(cmd),
+				StackMembership: stackMembership,
+				Errors:          errorsOut,
+				Strict:          strict,
+			})
+			if code != exitComplete {
+				os.Exit(code)
+			}
 		},
 	}
 	cmd.Flags().String("out", "", "Output path for the inventory file")
+	cmd.Flags().String("discover", "cost", "How to find the services to collect: cost, or cloudtrail to add services with recent write events")
+	cmd.Flags().Duration("window", 7*24*time.Hour, "CloudTrail window used with --discover=cloudtrail")
+	cmd.Flags().String("history", "", "History directory to add a snapshot of the inventory to")
+	cmd.Flags().String("source", "aws", "Where to read the inventory from: aws, prometheus for the scrape targets of a Prometheus server, or sentry for the projects of a Sentry organization")
+	cmd.Flags().String("prom-address", "", "Prometheus server address used with --source=prometheus, e.g. http://127.0.0.1:9090")
+	cmd.Flags().String("sentry-url", "https://sentry.io", "Sentry server URL used with --source=sentry")
+	cmd.Flags().String("sentry-org", "", "Sentry organization slug used with --source=sentry")
+	cmd.Flags().String("sentry-token", "", "Sentry auth token with org:read and project:read scopes used with --source=sentry, prefer SENTRY_AUTH_TOKEN or --sentry-env")
+	cmd.Flags().String("sentry-env", "", "Environment file, e.g. production.json, whose sentry datasource holds the organization and access_token used with --source=sentry")
+	cmd.Flags().String("errors", "", "Output path for the list of collectors and steps that failed")
+	cmd.Flags().Bool("stack-membership", false, "Record the CloudFormation stack of each resource, even when CloudFormation is not a top service")
+	cmd.Flags().Bool("strict", false, "Fail without writing the inventory when any collector or step fails")
+	addAWSConfigFlags(cmd)
 
 	cmd.MarkFlagRequired("out")
 	cmd.MarkFlagFilename("out")
+	cmd.MarkFlagFilename("errors")
+	cmd.MarkFlagFilename("sentry-env")
+	cmd.MarkFlagDirname("history")
 	return cmd
 }
 
-func makeInventory(out string) {
-	// Load AWS configuration
-	cfg, err := config.LoadDefaultConfig(context.TODO())
+// inventoryOptions controls where makeInventory reads the inventory from and how it finds the services to collect
+type inventoryOptions struct {
+	Source   string
+	Discover string
+	Window   time.Duration
+	// History directory the inventory is also saved to as a snapshot, none when empty
+	History     string
+	PromAddress string
+	SentryURL   string
+	SentryOrg   string
+	SentryToken string
+	// List the CloudFormation stacks to record the stack of each resource, even when CloudFormation is not a top service
+	StackMembership bool
+	// Credentials, region and endpoints of the aws source
+	AWS awsConfigOptions
+	// Output path of the collectors and steps that failed, not written when empty
+	Errors string
+	// Strict fails on any error instead of writing a partial inventory
+	Strict bool
+}
+
+// makeInventory writes the inventory and returns the exit code of inventory make
+func makeInventory(out string, options inventoryOptions) int {
+	results, errs, err := readInventorySource(options)
 	if err != nil {
-		log.Println("Error loading config:", err)
+		log.Println("Error collecting inventory:", err)
+		writeInventoryErrors(options.Errors, append(errs, newInventoryError("collect", "", "", err)))
+		if isAuthError(err) {
+			return exitAuthError
+		}
+		return exitFailure
+	}
+
+	writeInventoryErrors(options.Errors, errs)
+	code := inventoryExitCode(errs, len(results), options.Strict)
+	if len(errs) > 0 {
+		log.Printf("%d collectors or steps failed, the inventory is incomplete\n", len(errs))
+		if options.Strict {
+			log.Println("Not writing the inventory with --strict")
+			return code
+		}
+		if len(results) == 0 {
+			log.Println("Not writing the inventory, no service was collected")
+			return code
+		}
+	}
+
+	// Write results to a JSON file
+	if err := writeToJsonFile(results, out); err != nil {
+		log.Println("Error writing inventory:", err)
+		return exitFailure
+	}
+
+	if options.History != "" {
+		if err := saveSnapshot(options.History, results, time.Now()); err != nil {
+			log.Println("Error saving inventory snapshot:", err)
+			return exitFailure
+		}
+	}
+	return code
+}
+
+// readInventorySource collects the inventory from the source of the options. The errors of collectors that
+// failed without stopping the collection are returned with the inventory.
+func readInventorySource(options inventoryOptions) ([]ServiceMetadata, []InventoryError, error) {
+	switch options.Source {
+	case "prometheus":
+		results, err := listPrometheusJobs(options.PromAddress)
+		if err != nil {
+			return nil, nil, fmt.Errorf("listing Prometheus targets: %w", err)
+		}
+		return results, nil, nil
+
+	case "sentry":
+		results, errs, err := sentryInventory(options.SentryURL, options.SentryOrg, options.SentryToken)
+		if err != nil {
+			return nil, nil, fmt.Errorf("listing Sentry projects: %w", err)
+		}
+		return results, errs, nil
+
+	default:
+		// Load AWS configuration
+		cfg, err := loadAWSConfig(options.AWS)
+		if err != nil {
+			return nil, nil, fmt.Errorf("loading config: %w", err)
+		}
+		results, _, errs, err := collectInventory(cfg, options)
+		return results, errs, err
+	}
+}
+
+// writeInventoryErrors writes the error list when an output path is given, an empty list when nothing failed
+func writeInventoryErrors(path string, errs []InventoryError) {
+	if path == "" {
 		return
 	}
+	if errs == nil {
+		errs = []InventoryError{}
+	}
+	if err := writeToJsonFile(errs, path); err != nil {
+		log.Println("Error writing error list:", err)
+	}
+}
+
+// errServiceNotHandled is returned for service codes the inventory has no collector for
+var errServiceNotHandled = errors.New("service code not handled")
+
+// partialResourcesError is returned by listServiceResources along with the resources it listed when a
+// later step of the service's collector failed
+type partialResourcesError struct {
+	Step string
+	Err  error
+}
+
+func (e *partialResourcesError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *partialResourcesError) Unwrap() error {
+	return e.Err
+}
+
+// isPartialResourcesError reports whether a collector returned resources along with the step that failed
+func isPartialResourcesError(err error) bool {
+	var partial *partialResourcesError
+	return errors.As(err, &partial)
+}
+
+// collectorRun records how listing the resources of one service went
+type collectorRun struct {
+	ServiceName string
+	ServiceCode string
+	Duration    time.Duration
+	Err         error
+}
+
+// collectInventory lists the resources of the top services and links them to each other.
+// A collector or step failing does not stop the others, its error is returned in the error list and,
+// for collectors, in its run.
+func collectInventory(cfg aws.Config, options inventoryOptions) ([]ServiceMetadata, []collectorRun, []InventoryError, error) {
+	var errs []InventoryError
 
 	// Retrieve AWS account ID
 	accountID, err := getAccountID(cfg)
 	if err != nil {
-		log.Println("Error getting AWS account ID:", err)
-		return
+		return nil, nil, nil, fmt.Errorf("getting AWS account ID: %w", err)
 	}
 
 	// Retrieve AWS region from config
 	region := cfg.Region
 
 	// Get top services by billing
-	topServices, err := getTopServicesByCost(cfg)
+	topServices, costs, err := getServiceCosts(cfg)
 	if err != nil {
+		if options.Discover != "cloudtrail" {
+			return nil, nil, nil, fmt.Errorf("getting top services: %w", err)
+		}
 		log.Println("Error getting top services:", err)
-		return
+		errs = append(errs, newInventoryError("service_costs", "", "", err))
+	}
+
+	// Add services with recent write events, which may not show up in billing yet
+	var discovery cloudTrailDiscovery
+	if options.Discover == "cloudtrail" {
+		discovery, err = discoverServicesFromCloudTrail(cfg, options.Window)
+		if err != nil {
+			log.Println("Error discovering services from CloudTrail:", err)
+			errs = append(errs, newInventoryError("cloudtrail_discovery", "", "", err))
+		}
+		topServices = mergeDiscoveredServices(topServices, discovery)
 	}
 
 	// Iterate over top services and list resources for each
 	var results []ServiceMetadata
+	var ecsClusters ecsListing
+	var runs []collectorRun
 	for _, service := range topServices {
 		serviceCode, ok := mapServiceNameToCode(service)
 		if !ok {
@@ -104,134 +345,350 @@ func makeInventory(out string) {
 			continue
 		}
 
+		start := time.Now()
+		resources, err := listServiceResources(cfg, serviceCode, &ecsClusters)
+		if errors.Is(err, errServiceNotHandled) {
+			log.Printf("Service code not handled: %s\n", serviceCode)
+			continue
+		}
+		runs = append(runs, collectorRun{
+			ServiceName: service,
+			ServiceCode: serviceCode,
+			Duration:    time.Since(start),
+			Err:         err,
+		})
+		var partial *partialResourcesError
+		if errors.As(err, &partial) {
+			// The resources listed before the step failed are kept
+			log.Println("Error", err)
+			errs = append(errs, newInventoryError(partial.Step, service, serviceCode, partial.Err))
+		} else if err != nil {
+			log.Println("Error", err)
+			errs = append(errs, newInventoryError("list_resources", service, serviceCode, err))
+			continue
+		}
+
 		// Add metadata: AWS region and account ID
 		serviceMeta := ServiceMetadata{
 			ServiceName: service,
+			Resources:   resources,
 			MetaData: map[string]interface{}{
 				"region":     region,
 				"account_id": accountID,
 			},
 		}
+		if cost, ok := costs[service]; ok {
+			serviceMeta.MetaData["monthly_cost"] = cost
+		}
+		results = append(results, serviceMeta)
+	}
 
-		// List and describe resources for each service based on service code
-		switch serviceCode {
-		case "ec2":
-			resources, err := listEC2Instances(cfg)
-			if err != nil {
-				log.Println("Error listing EC2 instances:", err)
-				continue
-			}
-			serviceMeta.Resources = resources
+	// Keep ECS services running on Fargate and Aurora clusters listed once
+	referenceFargateWorkloads(results)
+	referenceAuroraClusters(results)
 
-		case "s3":
-			resources, err := listS3Buckets(cfg)
-			if err != nil {
-				log.Println("Error listing S3 buckets:", err)
-				continue
-			}
-			serviceMeta.Resources = resources
+	// Record the latest CloudTrail change of each resource
+	markRecentChanges(results, discovery)
 
-		case "dynamodb":
-			resources, err := listDynamoDBTables(cfg)
-			if err != nil {
-				log.Println("Error listing DynamoDB tables:", err)
-				continue
-			}
-			serviceMeta.Resources = resources
+	// Record which CloudFormation stack owns each resource. Listing the resources of every stack is slow, when
+	// CloudFormation itself is not a top service the stacks are only listed with StackMembership.
+	var stacks []ResourceMetadata
+	for _, serviceMeta := range results {
+		if code, _ := mapServiceNameToCode(serviceMeta.ServiceName); code == "cloudformation" {
+			stacks = serviceMeta.Resources
+		}
+	}
+	if stacks == nil && options.StackMembership {
+		stacks, err = listCloudFormationStacks(cfg)
+		if err != nil {
+			log.Println("Error listing CloudFormation stacks:", err)
+			errs = append(errs, newInventoryError("cloudformation_stacks", "", "cloudformation", err))
+		}
+	}
+	attachStackMembership(results, stacks)
 
-		case "apigateway":
-			// List API Gateway resources
-			resources, err := listApiGateways(cfg)
-			if err != nil {
-				log.Println("Error listing API Gateway resources:", err)
-				continue
-			}
-			serviceMeta.Resources = resources
+	// Link log groups to the resources that write to them
+	for _, serviceMeta := range results {
+		if code, _ := mapServiceNameToCode(serviceMeta.ServiceName); code == "cloudwatch" {
+			attachLogGroups(results, serviceMeta.Resources)
+		}
+	}
 
-		case "lambda":
-			resources, err := listLambdaFunctions(cfg)
-			if err != nil {
-				log.Println("Error listing Lambda functions:", err)
-				continue
-			}
-			serviceMeta.Resources = resources
+	// Resolve Route 53 alias and CNAME targets to the resources they point at
+	attachRecordTargets(results)
 
-		case "rds":
-			resources, err := listRDSInstances(cfg)
-			if err != nil {
-				log.Println("Error listing RDS instances:", err)
-				continue
-			}
-			serviceMeta.Resources = resources
+	// Map alarms onto the resources they watch and keep them with CloudWatch, even when it is not a top service
+	alarms, err := listCloudWatchAlarms(cfg)
+	if err != nil {
+		log.Println("Error listing CloudWatch alarms:", err)
+		errs = append(errs, newInventoryError("cloudwatch_alarms", "", "cloudwatch", err))
+	} else {
+		attachAlarms(results, alarms)
+		results = appendCloudWatchResources(results, alarms, region, accountID)
+	}
 
-		case "amazonebs":
-			resources, err := listEBSVolumes(cfg)
-			if err != nil {
-				log.Println("Error listing EBS volumes:", err)
-				continue
-			}
-			serviceMeta.Resources = resources
+	// Link the metric catalog to the resources each dimension set describes
+	if catalog := findMetricCatalog(results); catalog != nil {
+		linkMetricCatalog(catalog, results)
+	}
 
-		case "cloudfront":
-			resources, err := listCloudFrontDistributions(cfg)
-			if err != nil {
-				log.Println("Error listing CloudFront distributions:", err)
-				continue
-			}
-			serviceMeta.Resources = resources
+	return results, runs, errs, nil
+}
 
-		case "route53":
-			resources, err := listRoute53HostedZones(cfg)
-			if err != nil {
-				log.Println("Error listing Route 53 hosted zones:", err)
-				continue
-			}
-			serviceMeta.Resources = resources
+// listServiceResources lists and describes the resources of a service based on its service code.
+// The ECS clusters are listed once per collection, for both ECS and Fargate.
+func listServiceResources(cfg aws.Config, serviceCode string, ecsClusters *ecsListing) ([]ResourceMetadata, error) {
+	switch serviceCode {
+	case "ec2":
+		resources, err := listEC2Instances(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("listing EC2 instances: %w", err)
+		}
+		return resources, nil
 
-		case "vpc":
-			resources, err := listVPCs(cfg)
-			if err != nil {
-				log.Println("Error listing VPCs:", err)
-				continue
-			}
-			serviceMeta.Resources = resources
+	case "s3":
+		resources, err := listS3Buckets(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("listing S3 buckets: %w", err)
+		}
+		return resources, nil
 
-		case "elb":
-			resources, err := listELBs(cfg)
-			if err != nil {
-				log.Println("Error listing ELBs:", err)
-				continue
-			}
-			serviceMeta.Resources = resources
+	case "dynamodb":
+		resources, err := listDynamoDBTables(cfg)
+		if err != nil && !isPartialResourcesError(err) {
+			return nil, fmt.Errorf("listing DynamoDB tables: %w", err)
+		}
+		return resources, err
 
-		case "cloudwatch":
-			resources, err := listCloudWatchMetrics(cfg)
-			if err != nil {
-				log.Println("Error listing CloudWatch metrics:", err)
-				continue
-			}
-			serviceMeta.Resources = resources
+	case "apigateway":
+		// List API Gateway resources
+		resources, err := listApiGateways(cfg)
+		if err != nil && !isPartialResourcesError(err) {
+			return nil, fmt.Errorf("listing API Gateway resources: %w", err)
+		}
+		return resources, err
 
-		case "globalaccelerator":
-			resources, err := listGlobalAccelerators(cfg)
-			if err != nil {
-				log.Println("Error listing Global Accelerators:", err)
-				continue
-			}
-			serviceMeta.Resources = resources
+	case "lambda":
+		resources, err := listLambdaFunctions(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("listing Lambda functions: %w", err)
+		}
+		return resources, nil
 
-		// You can add more cases here for other services
+	case "rds":
+		resources, err := listRDSInstances(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("listing RDS instances: %w", err)
+		}
 
-		default:
-			log.Printf("Service code not handled: %s\n", serviceCode)
-			continue
+		// Aurora clusters are billed under RDS as well
+		clusters, err := listRDSClusters(cfg)
+		if err != nil {
+			return resources, &partialResourcesError{Step: "rds_clusters", Err: fmt.Errorf("listing RDS clusters: %w", err)}
 		}
+		return append(resources, clusters...), nil
 
-		results = append(results, serviceMeta)
+	case "amazonebs":
+		resources, err := listEBSVolumes(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("listing EBS volumes: %w", err)
+		}
+		return resources, nil
+
+	case "cloudfront":
+		resources, err := listCloudFrontDistributions(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("listing CloudFront distributions: %w", err)
+		}
+		return resources, nil
+
+	case "route53":
+		resources, err := listRoute53HostedZones(cfg)
+		if err != nil && !isPartialResourcesError(err) {
+			return nil, fmt.Errorf("listing Route 53 hosted zones: %w", err)
+		}
+		return resources, err
+
+	case "vpc":
+		resources, err := listVPCs(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("listing VPCs: %w", err)
+		}
+		return resources, nil
+
+	case "elb":
+		resources, detailsErr := listELBs(cfg)
+		if detailsErr != nil && !isPartialResourcesError(detailsErr) {
+			return nil, fmt.Errorf("listing ELBs: %w", detailsErr)
+		}
+
+		// Classic load balancers are billed under ELB as well. Missing load balancers are reported over
+		// missing details.
+		classic, err := listClassicLoadBalancers(cfg)
+		if err != nil {
+			return resources, &partialResourcesError{Step: "classic_load_balancers", Err: fmt.Errorf("listing classic load balancers: %w", err)}
+		}
+		return append(resources, classic...), detailsErr
+
+	case "cloudwatch":
+		resources, err := listCloudWatchMetrics(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("listing CloudWatch metrics: %w", err)
+		}
+
+		// CloudWatch Logs is billed under CloudWatch as well
+		logGroups, err := listLogGroups(cfg)
+		if err != nil && !isPartialResourcesError(err) {
+			return resources, &partialResourcesError{Step: "log_groups", Err: fmt.Errorf("listing CloudWatch log groups: %w", err)}
+		}
+		return append(resources, logGroups...), err
+
+	case "globalaccelerator":
+		resources, err := listGlobalAccelerators(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("listing Global Accelerators: %w", err)
+		}
+		return resources, nil
+
+	case "eks":
+		resources, err := listEKSClusters(cfg)
+		if err != nil && !isPartialResourcesError(err) {
+			return nil, fmt.Errorf("listing EKS clusters: %w", err)
+		}
+		return resources, err
+
+	case "ecs":
+		resources, err := ecsClusters.list(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("listing ECS clusters: %w", err)
+		}
+		return resources, nil
+
+	case "fargate":
+		resources, err := ecsClusters.list(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("listing Fargate workloads: %w", err)
+		}
+		return fargateWorkloads(resources), nil
+
+	case "sqs":
+		resources, err := listSQSQueues(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("listing SQS queues: %w", err)
+		}
+		return resources, nil
+
+	case "sns":
+		resources, err := listSNSTopics(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("listing SNS topics: %w", err)
+		}
+		return resources, nil
+
+	case "kinesis":
+		resources, err := listKinesisStreams(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("listing Kinesis streams: %w", err)
+		}
+		return resources, nil
+
+	case "stepfunctions":
+		resources, err := listStateMachines(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("listing Step Functions state machines: %w", err)
+		}
+		return resources, nil
+
+	case "aurora":
+		resources, err := listRDSClusters(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("listing RDS clusters: %w", err)
+		}
+		return resources, nil
+
+	case "redshift":
+		resources, err := listRedshiftClusters(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("listing Redshift clusters: %w", err)
+		}
+		return resources, nil
+
+	case "emr":
+		resources, err := listEMRClusters(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("listing EMR clusters: %w", err)
+		}
+		return resources, nil
+
+	case "glue":
+		resources, err := listGlueResources(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("listing Glue jobs and crawlers: %w", err)
+		}
+		return resources, nil
+
+	case "efs":
+		resources, err := listEFSFileSystems(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("listing EFS file systems: %w", err)
+		}
+		return resources, nil
+
+	case "sagemaker":
+		resources, err := listSageMakerEndpoints(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("listing SageMaker endpoints: %w", err)
+		}
+		return resources, nil
+
+	case "bedrock":
+		resources, err := listBedrockResources(cfg)
+		if err != nil && !isPartialResourcesError(err) {
+			return nil, fmt.Errorf("listing Bedrock resources: %w", err)
+		}
+		return resources, err
+
+	case "cognito":
+		resources, err := listCognitoUserPools(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("listing Cognito user pools: %w", err)
+		}
+		return resources, nil
+
+	case "kms":
+		resources, err := listKMSKeys(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("listing KMS keys: %w", err)
+		}
+		return resources, nil
+
+	case "secretsmanager":
+		resources, err := listSecrets(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("listing Secrets Manager secrets: %w", err)
+		}
+		return resources, nil
+
+	case "ses":
+		resources, err := listSESIdentities(cfg)
+		if err != nil && !isPartialResourcesError(err) {
+			return nil, fmt.Errorf("listing SES identities: %w", err)
+		}
+		return resources, err
+
+	case "cloudformation":
+		resources, err := listCloudFormationStacks(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("listing CloudFormation stacks: %w", err)
+		}
+		return resources, nil
+
+	// You can add more cases here for other services
+
+	default:
+		return nil, errServiceNotHandled
 	}
 
-	// Write results to a JSON file
-	writeToJsonFile(results, out)
 }
 
 // Get the AWS account ID using STS GetCallerIdentity
@@ -246,8 +703,8 @@ func getAccountID(cfg aws.Config) (string, error) {
 	return *result.Account, nil
 }
 
-// Get top services by cost from AWS Cost Explorer
-func getTopServicesByCost(cfg aws.Config) ([]string, error) {
+// Get top services by cost from AWS Cost Explorer, with the unblended cost of each over the last month
+func getServiceCosts(cfg aws.Config) ([]string, map[string]float64, error) {
 	svc := costexplorer.NewFromConfig(cfg)
 
 	endTime := time.Now()
@@ -270,63 +727,124 @@ func getTopServicesByCost(cfg aws.Config) ([]string, error) {
 
 	resp, err := svc.GetCostAndUsage(context.TODO(), input)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
+	topServices, costs := sumServiceCosts(resp.ResultsByTime)
+	return topServices, costs, nil
+}
+
+// sumServiceCosts adds up the cost of each service over all periods of the result. A month back from today
+// spans two calendar months, so the query returns two monthly periods.
+func sumServiceCosts(results []costTypes.ResultByTime) ([]string, map[string]float64) {
 	var topServices []string
-	for _, group := range resp.ResultsByTime[0].Groups {
-		topServices = append(topServices, group.Keys[0])
+	costs := make(map[string]float64)
+	for _, result := range results {
+		for _, group := range result.Groups {
+			if len(group.Keys) == 0 {
+				continue
+			}
+			if _, ok := costs[group.Keys[0]]; !ok {
+				topServices = append(topServices, group.Keys[0])
+			}
+			var amount float64
+			if cost, ok := group.Metrics["UnblendedCost"]; ok {
+				amount, _ = strconv.ParseFloat(aws.ToString(cost.Amount), 64)
+			}
+			costs[group.Keys[0]] += amount
+		}
 	}
+	return topServices, costs
+}
 
-	return topServices, nil
+// AWS service codes of the service names from AWS Cost Explorer and the event sources of CloudTrail
+var serviceNameCodes = map[string]string{
+	"Amazon Elastic Compute Cloud - Compute": "ec2",
+	"Amazon Simple Storage Service":          "s3",
+	"Amazon Relational Database Service":     "rds",
+	"Amazon DynamoDB":                        "dynamodb",
+	"AWS Lambda":                             "lambda",
+	"Amazon CloudFront":                      "cloudfront",
+	"Amazon Virtual Private Cloud":           "vpc",
+	"Amazon Simple Queue Service":            "sqs",
+	"Amazon Simple Notification Service":     "sns",
+	"Amazon Elastic Kubernetes Service":      "eks",
+	"Amazon Elastic Container Service":       "ecs",
+	"Amazon Aurora":                          "aurora",
+	"Amazon Redshift":                        "redshift",
+	"Amazon Elastic Block Store":             "ebs",
+	"AWS Identity and Access Management":     "iam",
+	"Amazon Route 53":                        "route53",
+	"AmazonCloudWatch":                       "cloudwatch",
+	"AWS Key Management Service":             "kms",
+	"AWS Glue - Data Integration":            "glue",
+	"Amazon SageMaker":                       "sagemaker",
+	"AWS Elastic Beanstalk":                  "elasticbeanstalk",
+	"AWS Fargate - Serverless Containers":    "fargate",
+	"Amazon Elastic File System":             "efs",
+	"AWS CloudFormation":                     "cloudformation",
+	"AWS Config":                             "config",
+	"Amazon Kinesis":                         "kinesis",
+	"Amazon API Gateway":                     "apigateway",
+	"AWS Step Functions":                     "stepfunctions",
+	"Amazon Elastic MapReduce":               "emr",
+	"AWS Secrets Manager":                    "secretsmanager",
+	"AWS CodeBuild":                          "codebuild",
+	"AWS Bedrock":                            "bedrock",
+	"Amazon Elastic Load Balancing":          "elb",
+	"AWS Global Accelerator":                 "globalaccelerator",
+	"Amazon Simple Email Service":            "ses",
+	"Amazon Cognito":                         "cognito",
+	"EC2 - Other":                            "amazonebs",
+
+	// CloudTrail event sources, see discoverServicesFromCloudTrail
+	"ec2.amazonaws.com":                  "ec2",
+	"s3.amazonaws.com":                   "s3",
+	"rds.amazonaws.com":                  "rds",
+	"dynamodb.amazonaws.com":             "dynamodb",
+	"lambda.amazonaws.com":               "lambda",
+	"cloudfront.amazonaws.com":           "cloudfront",
+	"sqs.amazonaws.com":                  "sqs",
+	"sns.amazonaws.com":                  "sns",
+	"eks.amazonaws.com":                  "eks",
+	"ecs.amazonaws.com":                  "ecs",
+	"redshift.amazonaws.com":             "redshift",
+	"route53.amazonaws.com":              "route53",
+	"monitoring.amazonaws.com":           "cloudwatch",
+	"logs.amazonaws.com":                 "cloudwatch",
+	"kms.amazonaws.com":                  "kms",
+	"glue.amazonaws.com":                 "glue",
+	"sagemaker.amazonaws.com":            "sagemaker",
+	"elasticfilesystem.amazonaws.com":    "efs",
+	"cloudformation.amazonaws.com":       "cloudformation",
+	"kinesis.amazonaws.com":              "kinesis",
+	"apigateway.amazonaws.com":           "apigateway",
+	"states.amazonaws.com":               "stepfunctions",
+	"elasticmapreduce.amazonaws.com":     "emr",
+	"secretsmanager.amazonaws.com":       "secretsmanager",
+	"bedrock.amazonaws.com":              "bedrock",
+	"elasticloadbalancing.amazonaws.com": "elb",
+	"globalaccelerator.amazonaws.com":    "globalaccelerator",
+	"ses.amazonaws.com":                  "ses",
+	"cognito-idp.amazonaws.com":          "cognito",
 }
 
 // Map service names from AWS Cost Explorer or CloudTrail to AWS service codes
 func mapServiceNameToCode(serviceName string) (string, bool) {
-	serviceMap := map[string]string{
-		"Amazon Elastic Compute Cloud - Compute": "ec2",
-		"Amazon Simple Storage Service":          "s3",
-		"Amazon Relational Database Service":     "rds",
-		"Amazon DynamoDB":                        "dynamodb",
-		"AWS Lambda":                             "lambda",
-		"Amazon CloudFront":                      "cloudfront",
-		"Amazon Virtual Private Cloud":           "vpc",
-		"Amazon Simple Queue Service":            "sqs",
-		"Amazon Simple Notification Service":     "sns",
-		"Amazon Elastic Kubernetes Service":      "eks",
-		"Amazon Elastic Container Service":       "ecs",
-		"Amazon Aurora":                          "aurora",
-		"Amazon Redshift":                        "redshift",
-		"Amazon Elastic Block Store":             "ebs",
-		"AWS Identity and Access Management":     "iam",
-		"Amazon Route 53":                        "route53",
-		"AmazonCloudWatch":                       "cloudwatch",
-		"AWS Key Management Service":             "kms",
-		"AWS Glue - Data Integration":            "glue",
-		"Amazon SageMaker":                       "sagemaker",
-		"AWS Elastic Beanstalk":                  "elasticbeanstalk",
-		"AWS Fargate - Serverless Containers":    "fargate",
-		"Amazon Elastic File System":             "efs",
-		"AWS CloudFormation":                     "cloudformation",
-		"AWS Config":                             "config",
-		"Amazon Kinesis":                         "kinesis",
-		"Amazon API Gateway":                     "apigateway",
-		"AWS Step Functions":                     "stepfunctions",
-		"Amazon Elastic MapReduce":               "emr",
-		"AWS Secrets Manager":                    "secretsmanager",
-		"AWS CodeBuild":                          "codebuild",
-		"AWS Bedrock":                            "bedrock",
-		"Amazon Elastic Load Balancing":          "elb",
-		"AWS Global Accelerator":                 "globalaccelerator",
-		"Amazon Simple Email Service":            "ses",
-		"Amazon Cognito":                         "cognito",
-		"EC2 - Other":                            "amazonebs",
-	}
-
-	code, exists := serviceMap[serviceName]
+	code, exists := serviceNameCodes[serviceName]
 	return code, exists
 }
 
+// costExplorerServiceName returns the Cost Explorer name of a service code, the name inventories are keyed by
+func costExplorerServiceName(code string) (string, bool) {
+	for name, nameCode := range serviceNameCodes {
+		if nameCode == code && !strings.HasSuffix(name, ".amazonaws.com") {
+			return name, true
+		}
+	}
+	return "", false
+}
+
 // ListGlobalAccelerators retrieves a list of Global Accelerators and their metadata
 func listGlobalAccelerators(cfg aws.Config) ([]ResourceMetadata, error) {
 	// Set the Global Accelerator endpoint explicitly
@@ -358,39 +876,6 @@ func listGlobalAccelerators(cfg aws.Config) ([]ResourceMetadata, error) {
 	return resources, nil
 }
 
-// ListCloudWatchMetrics retrieves a list of CloudWatch metrics and their metadata
-func listCloudWatchMetrics(cfg aws.Config) ([]ResourceMetadata, error) {
-	svc := cloudwatch.NewFromConfig(cfg)
-	input := &cloudwatch.ListMetricsInput{}
-	result, err := svc.ListMetrics(context.TODO(), input)
-	if err != nil {
-		return nil, err
-	}
-
-	var resources []ResourceMetadata
-	for _, metric := range result.Metrics {
-		// Build dimensions information for each metric
-		dimensions := make(map[string]string)
-		for _, dimension := range metric.Dimensions {
-			dimensions[*dimension.Name] = *dimension.Value
-		}
-
-		metadata := map[string]interface{}{
-			"namespace":   *metric.Namespace,
-			"metric_name": *metric.MetricName,
-			"dimensions":  dimensions,
-		}
-
-		// Using metric name as ResourceID for simplicity
-		resources = append(resources, ResourceMetadata{
-			ResourceID: *metric.MetricName,
-			MetaData:   metadata,
-		})
-	}
-
-	return resources, nil
-}
-
 func listELBs(cfg aws.Config) ([]ResourceMetadata, error) {
 	svc := elasticloadbalancingv2.NewFromConfig(cfg)
 	input := &elasticloadbalancingv2.DescribeLoadBalancersInput{}
@@ -401,6 +886,8 @@ func listELBs(cfg aws.Config) ([]ResourceMetadata, error) {
 	}
 
 	var resources []ResourceMetadata
+	var describeErr error
+	failed := 0
 	for _, elb := range result.LoadBalancers {
 		metadata := map[string]interface{}{
 			"dns_name":           *elb.DNSName,
@@ -410,17 +897,48 @@ func listELBs(cfg aws.Config) ([]ResourceMetadata, error) {
 			"availability_zones": elb.AvailabilityZones,
 		}
 
-		// Fetch additional details
-		metadata["attributes"] = getLoadBalancerAttributes(svc, *elb.LoadBalancerArn)
-		metadata["listeners"] = getListeners(svc, *elb.LoadBalancerArn)
-		targetGroups := getTargetGroups(svc, *elb.LoadBalancerArn)
+		// Fetch additional details, the load balancer is kept with whatever could be described
+		var detailErrs []error
+		attributes, err := getLoadBalancerAttributes(svc, *elb.LoadBalancerArn)
+		detailErrs = append(detailErrs, err)
+		metadata["attributes"] = attributes
+		listeners, err := getListeners(svc, *elb.LoadBalancerArn)
+		detailErrs = append(detailErrs, err)
+		metadata["listeners"] = listeners
+		tags, err := getEc2Tags(svc, *elb.LoadBalancerArn)
+		detailErrs = append(detailErrs, err)
+		metadata["tags"] = tags
+		targetGroups, err := getTargetGroups(svc, *elb.LoadBalancerArn)
 		metadata["target_groups"] = targetGroups
-		metadata["tags"] = getEc2Tags(svc, *elb.LoadBalancerArn)
 
-		// Fetch instances in target groups
+		// Fetch instances in target groups. When any of them cannot be described the number of healthy targets
+		// is unknown and left unset, a denied or throttled call must not make the load balancer look unused.
+		healthyTargets := 0
 		for _, tg := range targetGroups {
-			metadata["instances_"+*tg.TargetGroupArn] = getTargetGroupInstances(svc, *tg.TargetGroupArn)
+			if err != nil {
+				break
+			}
+			var targets []elbTypes.TargetHealthDescription
+			targets, err = getTargetGroupInstances(svc, *tg.TargetGroupArn)
+			metadata["instances_"+*tg.TargetGroupArn] = targets
+			for _, target := range targets {
+				if target.TargetHealth != nil && target.TargetHealth.State == elbTypes.TargetHealthStateEnumHealthy {
+					healthyTargets++
+				}
+			}
+		}
+		if err == nil {
+			metadata["healthy_targets"] = healthyTargets
+		}
+		detailErrs = append(detailErrs, err)
+		if err := errors.Join(detailErrs...); err != nil {
+			log.Printf("Error describing load balancer %s: %v\n", *elb.LoadBalancerArn, err)
+			failed++
+			describeErr = err
 		}
+		setCloudWatchRef(metadata, loadBalancerNamespace(elb.Type), map[string]string{
+			"LoadBalancer": loadBalancerDimension(*elb.LoadBalancerArn),
+		})
 
 		resources = append(resources, ResourceMetadata{
 			ResourceID: *elb.LoadBalancerArn,
@@ -428,62 +946,172 @@ func listELBs(cfg aws.Config) ([]ResourceMetadata, error) {
 		})
 	}
 
+	if describeErr != nil {
+		return resources, &partialResourcesError{Step: "elb_details",
+			Err: fmt.Errorf("describing %d load balancers: %w", failed, describeErr)}
+	}
+	return resources, nil
+}
+
+// listClassicLoadBalancers retrieves classic load balancers with their registered instances, keyed by name
+func listClassicLoadBalancers(cfg aws.Config) ([]ResourceMetadata, error) {
+	svc := elasticloadbalancing.NewFromConfig(cfg)
+
+	var resources []ResourceMetadata
+	paginator := elasticloadbalancing.NewDescribeLoadBalancersPaginator(svc, &elasticloadbalancing.DescribeLoadBalancersInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return nil, err
+		}
+		for _, lb := range page.LoadBalancerDescriptions {
+			resources = append(resources, ResourceMetadata{
+				ResourceID: aws.ToString(lb.LoadBalancerName),
+				MetaData:   classicLoadBalancerMetadata(lb),
+			})
+		}
+	}
+
+	// DescribeTags accepts up to 20 load balancers per call
+	var names []string
+	for _, resource := range resources {
+		names = append(names, resource.ResourceID)
+	}
+	tags := make(map[string]map[string]string)
+	for _, batch := range chunkStrings(names, 20) {
+		result, err := svc.DescribeTags(context.TODO(), &elasticloadbalancing.DescribeTagsInput{LoadBalancerNames: batch})
+		if err != nil {
+			log.Println("Error describing classic load balancer tags:", err)
+			break
+		}
+		for _, description := range result.TagDescriptions {
+			tagMap := make(map[string]string)
+			for _, tag := range description.Tags {
+				tagMap[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+			}
+			tags[aws.ToString(description.LoadBalancerName)] = tagMap
+		}
+	}
+	for _, resource := range resources {
+		if tagMap, ok := tags[resource.ResourceID]; ok {
+			resource.MetaData["tags"] = tagMap
+		}
+	}
+
 	return resources, nil
 }
 
-func getLoadBalancerAttributes(svc *elasticloadbalancingv2.Client, lbArn string) []elbTypes.LoadBalancerAttribute {
+// classicLoadBalancerMetadata maps a classic load balancer, whose DNS names look like those of application
+// load balancers
+func classicLoadBalancerMetadata(lb clbTypes.LoadBalancerDescription) map[string]interface{} {
+	name := aws.ToString(lb.LoadBalancerName)
+
+	var instances []string
+	for _, instance := range lb.Instances {
+		instances = append(instances, aws.ToString(instance.InstanceId))
+	}
+	var listeners []map[string]interface{}
+	for _, description := range lb.ListenerDescriptions {
+		if listener := description.Listener; listener != nil {
+			listeners = append(listeners, map[string]interface{}{
+				"protocol":          aws.ToString(listener.Protocol),
+				"port":              listener.LoadBalancerPort,
+				"instance_protocol": aws.ToString(listener.InstanceProtocol),
+				"instance_port":     aws.ToInt32(listener.InstancePort),
+			})
+		}
+	}
+
+	metadata := map[string]interface{}{
+		"resource_type":      "classic_load_balancer",
+		"load_balancer_type": "classic",
+		"name":               name,
+		"dns_name":           aws.ToString(lb.DNSName),
+		"scheme":             aws.ToString(lb.Scheme),
+		"vpc_id":             aws.ToString(lb.VPCId),
+		"availability_zones": lb.AvailabilityZones,
+		"security_groups":    lb.SecurityGroups,
+		"instances":          instances,
+		"listeners":          listeners,
+		"created_time":       lb.CreatedTime,
+	}
+	setCloudWatchRef(metadata, "AWS/ELB", map[string]string{"LoadBalancerName": name})
+	return metadata
+}
+
+// loadBalancerNamespace returns the CloudWatch namespace that load balancers of the given type publish to
+func loadBalancerNamespace(lbType elbTypes.LoadBalancerTypeEnum) string {
+	switch lbType {
+	case elbTypes.LoadBalancerTypeEnumNetwork:
+		return "AWS/NetworkELB"
+	case elbTypes.LoadBalancerTypeEnumGateway:
+		return "AWS/GatewayELB"
+	default:
+		return "AWS/ApplicationELB"
+	}
+}
+
+// loadBalancerDimension returns the LoadBalancer dimension value, the ARN suffix after "loadbalancer/"
+func loadBalancerDimension(lbArn string) string {
+	if i := strings.Index(lbArn, ":loadbalancer/"); i >= 0 {
+		return lbArn[i+len(":loadbalancer/"):]
+	}
+	return lbArn
+}
+
+func getLoadBalancerAttributes(svc *elasticloadbalancingv2.Client, lbArn string) ([]elbTypes.LoadBalancerAttribute, error) {
 	input := &elasticloadbalancingv2.DescribeLoadBalancerAttributesInput{
 		LoadBalancerArn: aws.String(lbArn),
 	}
 	result, err := svc.DescribeLoadBalancerAttributes(context.TODO(), input)
 	if err != nil {
-		return nil
+		return nil, err
 	}
-	return result.Attributes
+	return result.Attributes, nil
 }
 
-func getListeners(svc *elasticloadbalancingv2.Client, lbArn string) []elbTypes.Listener {
+func getListeners(svc *elasticloadbalancingv2.Client, lbArn string) ([]elbTypes.Listener, error) {
 	input := &elasticloadbalancingv2.DescribeListenersInput{
 		LoadBalancerArn: aws.String(lbArn),
 	}
 	result, err := svc.DescribeListeners(context.TODO(), input)
 	if err != nil {
-		return nil
+		return nil, err
 	}
-	return result.Listeners
+	return result.Listeners, nil
 }
 
-func getTargetGroups(svc *elasticloadbalancingv2.Client, lbArn string) []elbTypes.TargetGroup {
+func getTargetGroups(svc *elasticloadbalancingv2.Client, lbArn string) ([]elbTypes.TargetGroup, error) {
 	input := &elasticloadbalancingv2.DescribeTargetGroupsInput{
 		LoadBalancerArn: aws.String(lbArn),
 	}
 	result, err := svc.DescribeTargetGroups(context.TODO(), input)
 	if err != nil {
-		return nil
+		return nil, err
 	}
-	return result.TargetGroups
+	return result.TargetGroups, nil
 }
 
-func getTargetGroupInstances(svc *elasticloadbalancingv2.Client, tgArn string) []elbTypes.TargetHealthDescription {
+func getTargetGroupInstances(svc *elasticloadbalancingv2.Client, tgArn string) ([]elbTypes.TargetHealthDescription, error) {
 	input := &elasticloadbalancingv2.DescribeTargetHealthInput{
 		TargetGroupArn: aws.String(tgArn),
 	}
 	result, err := svc.DescribeTargetHealth(context.TODO(), input)
 	if err != nil {
-		return nil
+		return nil, err
 	}
-	return result.TargetHealthDescriptions
+	return result.TargetHealthDescriptions, nil
 }
 
-func getEc2Tags(svc *elasticloadbalancingv2.Client, lbArn string) []elbTypes.TagDescription {
+func getEc2Tags(svc *elasticloadbalancingv2.Client, lbArn string) ([]elbTypes.TagDescription, error) {
 	input := &elasticloadbalancingv2.DescribeTagsInput{
 		ResourceArns: []string{lbArn},
 	}
 	result, err := svc.DescribeTags(context.TODO(), input)
 	if err != nil {
-		return nil
+		return nil, err
 	}
-	return result.TagDescriptions
+	return result.TagDescriptions, nil
 }
 
 // ListVPCs retrieves a list of VPCs and their metadata
@@ -588,35 +1216,6 @@ func getSecurityGroupsForVPC(svc *ec2.Client, vpcID string) []string {
 	return securityGroups
 }
 
-func listRoute53HostedZones(cfg aws.Config) ([]ResourceMetadata, error) {
-	svc := route53.NewFromConfig(cfg)
-	input := &route53.ListHostedZonesInput{}
-	result, err := svc.ListHostedZones(context.TODO(), input)
-	if err != nil {
-		return nil, err
-	}
-
-	var resources []ResourceMetadata
-	for _, zone := range result.HostedZones {
-		metadata := map[string]interface{}{
-			"name":                  *zone.Name,
-			"resource_record_count": zone.ResourceRecordSetCount,
-			"private_zone":          zone.Config.PrivateZone,
-		}
-
-		if zone.Config.Comment != nil {
-			metadata["comment"] = *zone.Config.Comment
-		}
-
-		resources = append(resources, ResourceMetadata{
-			ResourceID: *zone.Id,
-			MetaData:   metadata,
-		})
-	}
-
-	return resources, nil
-}
-
 // listCloudFrontDistributions retrieves a list of CloudFront distributions and their metadata
 func listCloudFrontDistributions(cfg aws.Config) ([]ResourceMetadata, error) {
 	svc := cloudfront.NewFromConfig(cfg)
@@ -639,6 +1238,7 @@ func listCloudFrontDistributions(cfg aws.Config) ([]ResourceMetadata, error) {
 			if distribution.Comment != nil {
 				metadata["comment"] = *distribution.Comment
 			}
+			setCloudWatchRef(metadata, "AWS/CloudFront", map[string]string{"DistributionId": *distribution.Id, "Region": "Global"})
 
 			resources = append(resources, ResourceMetadata{
 				ResourceID: *distribution.Id,
@@ -676,6 +1276,7 @@ func listEC2Instances(cfg aws.Config) ([]ResourceMetadata, error) {
 				"key_name":          instance.KeyName,
 				"volumes":           getVolumes(svc, instance.InstanceId),
 			}
+			setCloudWatchRef(metadata, "AWS/EC2", map[string]string{"InstanceId": *instance.InstanceId})
 
 			resources = append(resources, ResourceMetadata{
 				ResourceID: *instance.InstanceId,
@@ -754,6 +1355,7 @@ func listEBSVolumes(cfg aws.Config) ([]ResourceMetadata, error) {
 		if volume.KmsKeyId != nil {
 			metadata["kms_key_id"] = *volume.KmsKeyId
 		}
+		setCloudWatchRef(metadata, "AWS/EBS", map[string]string{"VolumeId": *volume.VolumeId})
 
 		resources = append(resources, ResourceMetadata{
 			ResourceID: *volume.VolumeId,
@@ -774,41 +1376,42 @@ func listS3Buckets(cfg aws.Config) ([]ResourceMetadata, error) {
 
 	var resources []ResourceMetadata
 	for _, bucket := range result.Buckets {
+		metadata := map[string]interface{}{
+			"creation_date": *bucket.CreationDate,
+		}
+		setCloudWatchRef(metadata, "AWS/S3", map[string]string{"BucketName": *bucket.Name})
+
 		resources = append(resources, ResourceMetadata{
 			ResourceID: *bucket.Name,
-			MetaData: map[string]interface{}{
-				"creation_date": *bucket.CreationDate,
-			},
+			MetaData:   metadata,
 		})
 	}
 
 	return resources, nil
 }
 
-func writeToJsonFile(data []ServiceMetadata, filename string) {
+func writeToJsonFile(data interface{}, filename string) error {
 	// Ensure the directory exists by getting the directory part of the filename
 	dir := filepath.Dir(filename)
 	err := os.MkdirAll(dir, os.ModePerm)
 	if err != nil {
-		log.Println("Error creating directory:", err)
-		return
+		return fmt.Errorf("creating directory: %w", err)
 	}
 
 	// Create the file
 	file, err := os.Create(filename)
 	if err != nil {
-		log.Println("Error creating file:", err)
-		return
+		return fmt.Errorf("creating file: %w", err)
 	}
 	defer file.Close()
 
 	// Write JSON data with indentation
 	encoder := json.NewEncoder(file)
 	encoder.SetIndent("", "  ")
-	err = encoder.Encode(data)
-	if err != nil {
-		log.Println("Error writing JSON data:", err)
+	if err := encoder.Encode(data); err != nil {
+		return fmt.Errorf("writing JSON data: %w", err)
 	}
+	return file.Close()
 }
 
 // List DynamoDB tables and describe each
@@ -820,68 +1423,35 @@ func listDynamoDBTables(cfg aws.Config) ([]ResourceMetadata, error) {
 	}
 
 	var resources []ResourceMetadata
+	var describeErr error
+	failed := 0
 	for _, tableName := range result.TableNames {
 		desc, err := svc.DescribeTable(context.TODO(), &dynamodb.DescribeTableInput{
 			TableName: aws.String(tableName),
 		})
 		if err != nil {
 			log.Printf("Error describing DynamoDB table: %s\n", tableName)
+			failed++
+			describeErr = err
 			continue
 		}
 
-		resources = append(resources, ResourceMetadata{
-			ResourceID: tableName,
-			MetaData: map[string]interface{}{
-				"table_status": desc.Table.TableStatus,
-				"item_count":   *desc.Table.ItemCount,
-			},
-		})
-	}
-
-	return resources, nil
-}
-
-// List API Gateway REST APIs
-func listApiGateways(cfg aws.Config) ([]ResourceMetadata, error) {
-	svc := apigateway.NewFromConfig(cfg)
-	result, err := svc.GetRestApis(context.TODO(), &apigateway.GetRestApisInput{})
-	if err != nil {
-		return nil, err
-	}
+		metadata := map[string]interface{}{
+			"table_status": desc.Table.TableStatus,
+			"item_count":   *desc.Table.ItemCount,
+		}
+		setCloudWatchRef(metadata, "AWS/DynamoDB", map[string]string{"TableName": tableName})
 
-	var resources []ResourceMetadata
-	for _, api := range result.Items {
 		resources = append(resources, ResourceMetadata{
-			ResourceID: *api.Id,
-			MetaData: map[string]interface{}{
-				"name":        *api.Name,
-				"description": *api.Description,
-			},
+			ResourceID: tableName,
+			MetaData:   metadata,
 		})
 	}
 
-	return resources, nil
-}
-
-// List Lambda functions and describe each
-func listLambdaFunctions(cfg aws.Config) ([]ResourceMetadata, error) {
-	svc := lambda.NewFromConfig(cfg)
-	result, err := svc.ListFunctions(context.TODO(), &lambda.ListFunctionsInput{})
-	if err != nil {
-		return nil, err
-	}
-
-	var resources []ResourceMetadata
-	for _, function := range result.Functions {
-		resources = append(resources, ResourceMetadata{
-			ResourceID: *function.FunctionName,
-			MetaData: map[string]interface{}{
-				"runtime":     function.Runtime,
-				"last_update": *function.LastModified,
-			},
-		})
+	if describeErr != nil {
+		return resources, &partialResourcesError{Step: "dynamodb_tables",
+			Err: fmt.Errorf("describing %d DynamoDB tables: %w", failed, describeErr)}
 	}
-
 	return resources, nil
 }
 
@@ -895,13 +1465,30 @@ func listRDSInstances(cfg aws.Config) ([]ResourceMetadata, error) {
 
 	var resources []ResourceMetadata
 	for _, dbInstance := range result.DBInstances {
+		if !isRDSEngine(aws.ToString(dbInstance.Engine)) {
+			continue
+		}
+		metadata := map[string]interface{}{
+			"resource_type": "instance",
+			"engine":        *dbInstance.Engine,
+			"instance_type": *dbInstance.DBInstanceClass,
+			"status":        *dbInstance.DBInstanceStatus,
+		}
+
+		// Route 53 records point at the endpoint, see attachRecordTargets
+		if dbInstance.Endpoint != nil {
+			metadata["endpoint"] = aws.ToString(dbInstance.Endpoint.Address)
+		}
+
+		// Aurora instances belong to a cluster, see listRDSClusters
+		if dbInstance.DBClusterIdentifier != nil {
+			metadata["db_cluster_identifier"] = *dbInstance.DBClusterIdentifier
+		}
+		setCloudWatchRef(metadata, "AWS/RDS", map[string]string{"DBInstanceIdentifier": *dbInstance.DBInstanceIdentifier})
+
 		resources = append(resources, ResourceMetadata{
 			ResourceID: *dbInstance.DBInstanceIdentifier,
-			MetaData: map[string]interface{}{
-				"engine":        *dbInstance.Engine,
-				"instance_type": *dbInstance.DBInstanceClass,
-				"status":        *dbInstance.DBInstanceStatus,
-			},
+			MetaData:   metadata,
 		})
 	}
 
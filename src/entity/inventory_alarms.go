@@ -0,0 +1,205 @@
+package entity
+
+import (
+	"context"
+	"log"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	cwTypes "github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/spf13/cobra"
+)
+
+// Alarms record when their state last changed
+func init() {
+	addVolatileMetadataKeys("state_updated")
+}
+
+// CoverageItem is a resource without alarms or an alarm without a resource
+type CoverageItem struct {
+	ServiceName string                 `json:"service_name"`
+	ResourceID  string                 `json:"resource_id"`
+	MetaData    map[string]interface{} `json:"metadata"`
+}
+
+// CoverageReport lists the gaps between the inventory and its CloudWatch alarms
+type CoverageReport struct {
+	UnalarmedResources []CoverageItem `json:"unalarmed_resources"`
+	OrphanedAlarms     []CoverageItem `json:"orphaned_alarms"`
+}
+
+func coverageInventoryCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "coverage",
+		Short: "Report resources without alarms and alarms on resources that no longer exist",
+		Run: func(cmd *cobra.Command, args []string) {
+			in, _ := cmd.Flags().GetString("in")
+			out, _ := cmd.Flags().GetString("out")
+			log.Printf("Creating alarm coverage report from %s at %s\n", in, out)
+			services, err := readInventoryFile(in)
+			if err != nil {
+				log.Printf("Error reading inventory: %v\n", err)
+				return
+			}
+			report := makeCoverageReport(services)
+			log.Printf("Found %d resources without alarms and %d orphaned alarms\n",
+				len(report.UnalarmedResources), len(report.OrphanedAlarms))
+			if err := writeToJsonFile(report, out); err != nil {
+				log.Printf("Error writing coverage report: %v\n", err)
+			}
+		},
+	}
+	cmd.Flags().String("in", "", "Input path of the inventory file")
+	cmd.Flags().String("out", "", "Output path for the coverage report")
+
+	cmd.MarkFlagRequired("in")
+	cmd.MarkFlagRequired("out")
+	cmd.MarkFlagFilename("in")
+	cmd.MarkFlagFilename("out")
+	return cmd
+}
+
+// listCloudWatchAlarms retrieves metric alarms with their state and the dimensions they watch
+func listCloudWatchAlarms(cfg aws.Config) ([]ResourceMetadata, error) {
+	svc := cloudwatch.NewFromConfig(cfg)
+
+	var resources []ResourceMetadata
+	paginator := cloudwatch.NewDescribeAlarmsPaginator(svc, &cloudwatch.DescribeAlarmsInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return nil, err
+		}
+
+		for _, alarm := range page.MetricAlarms {
+			metadata := map[string]interface{}{
+				"resource_type":       "alarm",
+				"name":                aws.ToString(alarm.AlarmName),
+				"state":               alarm.StateValue,
+				"state_reason":        aws.ToString(alarm.StateReason),
+				"state_updated":       alarm.StateUpdatedTimestamp,
+				"actions_enabled":     aws.ToBool(alarm.ActionsEnabled),
+				"alarm_actions":       alarm.AlarmActions,
+				"comparison_operator": alarm.ComparisonOperator,
+				"threshold":           aws.ToFloat64(alarm.Threshold),
+				"namespace":           aws.ToString(alarm.Namespace),
+				"metric_name":         aws.ToString(alarm.MetricName),
+				"dimensions":          getAlarmDimensions(alarm.Dimensions),
+			}
+
+			// Metric math alarms carry their metrics in queries instead
+			if alarm.Namespace == nil {
+				for _, query := range alarm.Metrics {
+					if query.MetricStat == nil || query.MetricStat.Metric == nil {
+						continue
+					}
+					metric := query.MetricStat.Metric
+					metadata["namespace"] = aws.ToString(metric.Namespace)
+					metadata["metric_name"] = aws.ToString(metric.MetricName)
+					metadata["dimensions"] = getAlarmDimensions(metric.Dimensions)
+					break
+				}
+			}
+
+			resources = append(resources, ResourceMetadata{
+				ResourceID: aws.ToString(alarm.AlarmArn),
+				MetaData:   metadata,
+			})
+		}
+	}
+
+	return resources, nil
+}
+
+func getAlarmDimensions(dimensions []cwTypes.Dimension) map[string]string {
+	dimensionMap := make(map[string]string)
+	for _, dimension := range dimensions {
+		dimensionMap[aws.ToString(dimension.Name)] = aws.ToString(dimension.Value)
+	}
+	return dimensionMap
+}
+
+// attachAlarms maps each alarm onto the inventory resource it watches. The resource gets the alarm names,
+// the alarm gets the resource, and alarms on a kind of resource the inventory knows but cannot find are
+// marked as orphaned.
+func attachAlarms(services []ServiceMetadata, alarms []ResourceMetadata) {
+	index := newCloudWatchIndex(services)
+	for _, alarm := range alarms {
+		namespace := metadataString(alarm.MetaData, "namespace")
+		dimensions := metadataStringMap(alarm.MetaData, "dimensions")
+		if len(dimensions) == 0 {
+			continue
+		}
+
+		match, ok := index.match(namespace, dimensions)
+		if !ok {
+			alarm.MetaData["orphaned"] = index.targetsKnownKind(namespace, dimensions)
+			continue
+		}
+
+		alarm.MetaData["resource_id"] = match.resource.ResourceID
+		alarm.MetaData["service_name"] = match.serviceName
+		alarmNames, _ := match.resource.MetaData["alarms"].([]string)
+		match.resource.MetaData["alarms"] = append(alarmNames, metadataString(alarm.MetaData, "name"))
+	}
+}
+
+// appendCloudWatchResources adds resources to the CloudWatch service, creating it when it was not collected
+func appendCloudWatchResources(services []ServiceMetadata, resources []ResourceMetadata, region, accountID string) []ServiceMetadata {
+	for i, service := range services {
+		if code, _ := mapServiceNameToCode(service.ServiceName); code == "cloudwatch" {
+			services[i].Resources = append(services[i].Resources, resources...)
+			return services
+		}
+	}
+	return append(services, ServiceMetadata{
+		ServiceName: "AmazonCloudWatch",
+		Resources:   resources,
+		MetaData: map[string]interface{}{
+			"region":     region,
+			"account_id": accountID,
+		},
+	})
+}
+
+// makeCoverageReport lists monitorable resources without alarms and orphaned alarms from an inventory
+func makeCoverageReport(services []ServiceMetadata) CoverageReport {
+	report := CoverageReport{
+		UnalarmedResources: []CoverageItem{},
+		OrphanedAlarms:     []CoverageItem{},
+	}
+	for _, service := range services {
+		for _, resource := range service.Resources {
+			if metadataString(resource.MetaData, "resource_type") == "alarm" {
+				if orphaned, _ := resource.MetaData["orphaned"].(bool); orphaned {
+					report.OrphanedAlarms = append(report.OrphanedAlarms, CoverageItem{
+						ServiceName: service.ServiceName,
+						ResourceID:  resource.ResourceID,
+						MetaData: map[string]interface{}{
+							"name":        resource.MetaData["name"],
+							"state":       resource.MetaData["state"],
+							"namespace":   resource.MetaData["namespace"],
+							"metric_name": resource.MetaData["metric_name"],
+							"dimensions":  resource.MetaData["dimensions"],
+						},
+					})
+				}
+				continue
+			}
+
+			// Only resources with CloudWatch metrics can have alarms
+			if metadataString(resource.MetaData, "cloudwatch_namespace") == "" || metadataLen(resource.MetaData, "alarms") > 0 {
+				continue
+			}
+			report.UnalarmedResources = append(report.UnalarmedResources, CoverageItem{
+				ServiceName: service.ServiceName,
+				ResourceID:  resource.ResourceID,
+				MetaData: map[string]interface{}{
+					"cloudwatch_namespace":  resource.MetaData["cloudwatch_namespace"],
+					"cloudwatch_dimensions": resource.MetaData["cloudwatch_dimensions"],
+				},
+			})
+		}
+	}
+	return report
+}
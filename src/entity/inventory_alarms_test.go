@@ -0,0 +1,52 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAlarmCoverage(t *testing.T) {
+	services := []ServiceMetadata{
+		{ServiceName: "Amazon Elastic Compute Cloud - Compute", Resources: []ResourceMetadata{
+			{ResourceID: "i-1", MetaData: map[string]interface{}{
+				"cloudwatch_namespace":  "AWS/EC2",
+				"cloudwatch_dimensions": map[string]string{"InstanceId": "i-1"},
+			}},
+			{ResourceID: "i-2", MetaData: map[string]interface{}{
+				"cloudwatch_namespace":  "AWS/EC2",
+				"cloudwatch_dimensions": map[string]string{"InstanceId": "i-2"},
+			}},
+		}},
+	}
+	alarms := []ResourceMetadata{
+		{ResourceID: "arn:alarm:cpu-1", MetaData: map[string]interface{}{
+			"resource_type": "alarm", "name": "cpu-1", "namespace": "AWS/EC2",
+			"dimensions": map[string]string{"InstanceId": "i-1"},
+		}},
+		{ResourceID: "arn:alarm:cpu-old", MetaData: map[string]interface{}{
+			"resource_type": "alarm", "name": "cpu-old", "namespace": "AWS/EC2",
+			"dimensions": map[string]string{"InstanceId": "i-deleted"},
+		}},
+		// The inventory does not know Auto Scaling groups, so this alarm cannot be judged
+		{ResourceID: "arn:alarm:asg", MetaData: map[string]interface{}{
+			"resource_type": "alarm", "name": "asg", "namespace": "AWS/EC2",
+			"dimensions": map[string]string{"AutoScalingGroupName": "web"},
+		}},
+	}
+
+	attachAlarms(services, alarms)
+	services = appendCloudWatchResources(services, alarms, "us-east-1", "123")
+
+	assert.Equal(t, []string{"cpu-1"}, services[0].Resources[0].MetaData["alarms"])
+	assert.Equal(t, "i-1", alarms[0].MetaData["resource_id"])
+	assert.Equal(t, true, alarms[1].MetaData["orphaned"])
+	assert.Equal(t, false, alarms[2].MetaData["orphaned"])
+	assert.Equal(t, "AmazonCloudWatch", services[1].ServiceName)
+
+	report := makeCoverageReport(services)
+	assert.Len(t, report.UnalarmedResources, 1)
+	assert.Equal(t, "i-2", report.UnalarmedResources[0].ResourceID)
+	assert.Len(t, report.OrphanedAlarms, 1)
+	assert.Equal(t, "arn:alarm:cpu-old", report.OrphanedAlarms[0].ResourceID)
+}
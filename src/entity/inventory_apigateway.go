@@ -0,0 +1,353 @@
+package entity
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/apigateway"
+	apiTypes "github.com/aws/aws-sdk-go-v2/service/apigateway/types"
+	"github.com/aws/aws-sdk-go-v2/service/apigatewayv2"
+	apiv2Types "github.com/aws/aws-sdk-go-v2/service/apigatewayv2/types"
+)
+
+// Number of most recent deployments kept per API, auto deployed APIs can have thousands
+const maxApiDeployments = 10
+
+// listApiGateways retrieves REST APIs and HTTP/WebSocket APIs with their stages, deployments,
+// usage plans and custom domains
+func listApiGateways(cfg aws.Config) ([]ResourceMetadata, error) {
+	svc := apigateway.NewFromConfig(cfg)
+	v2Svc := apigatewayv2.NewFromConfig(cfg)
+
+	usagePlans := getUsagePlans(svc)
+	domains := getApiDomainMappings(v2Svc)
+
+	var resources []ResourceMetadata
+	paginator := apigateway.NewGetRestApisPaginator(svc, &apigateway.GetRestApisInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return nil, err
+		}
+
+		for _, api := range page.Items {
+			id := aws.ToString(api.Id)
+			metadata := restApiMetadata(api, usagePlans, domains)
+			metadata["stages"] = getRestApiStages(svc, id, aws.ToString(api.Name))
+			count, deployments := getRestApiDeployments(svc, id)
+			metadata["deployment_count"] = count
+			metadata["recent_deployments"] = deployments
+
+			resources = append(resources, ResourceMetadata{
+				ResourceID: id,
+				MetaData:   metadata,
+			})
+		}
+	}
+
+	apis, err := listApiGatewayV2Apis(v2Svc, domains)
+	if err != nil {
+		return resources, &partialResourcesError{Step: "apigateway_v2", Err: fmt.Errorf("listing API Gateway v2 APIs: %w", err)}
+	}
+	return append(resources, apis...), nil
+}
+
+// restApiMetadata maps a REST API with its usage plans and custom domains, keyed by API ID
+func restApiMetadata(api apiTypes.RestApi, usagePlans, domains map[string][]map[string]interface{}) map[string]interface{} {
+	id := aws.ToString(api.Id)
+	name := aws.ToString(api.Name)
+
+	var endpointTypes []string
+	if api.EndpointConfiguration != nil {
+		for _, endpointType := range api.EndpointConfiguration.Types {
+			endpointTypes = append(endpointTypes, string(endpointType))
+		}
+	}
+
+	metadata := map[string]interface{}{
+		"resource_type":  "rest_api",
+		"name":           name,
+		"description":    aws.ToString(api.Description),
+		"created_date":   api.CreatedDate,
+		"endpoint_types": endpointTypes,
+		"api_key_source": api.ApiKeySource,
+		"tags":           api.Tags,
+		"usage_plans":    usagePlans[id],
+		"custom_domains": domains[id],
+	}
+	// REST API metrics are published by name, not ID
+	setCloudWatchRef(metadata, "AWS/ApiGateway", map[string]string{"ApiName": name})
+	return metadata
+}
+
+func getRestApiStages(svc *apigateway.Client, apiID, apiName string) []map[string]interface{} {
+	result, err := svc.GetStages(context.TODO(), &apigateway.GetStagesInput{
+		RestApiId: aws.String(apiID),
+	})
+	if err != nil {
+		return nil
+	}
+
+	var stages []map[string]interface{}
+	for _, stage := range result.Item {
+		stageName := aws.ToString(stage.StageName)
+		metadata := map[string]interface{}{
+			"stage_name":            stageName,
+			"deployment_id":         aws.ToString(stage.DeploymentId),
+			"tracing_enabled":       stage.TracingEnabled,
+			"cache_cluster_enabled": stage.CacheClusterEnabled,
+			"last_updated":          stage.LastUpdatedDate,
+			"cloudwatch_dimensions": map[string]string{"ApiName": apiName, "Stage": stageName},
+		}
+		if stage.WebAclArn != nil {
+			metadata["web_acl_arn"] = *stage.WebAclArn
+		}
+		if stage.AccessLogSettings != nil {
+			metadata["access_log_destination"] = aws.ToString(stage.AccessLogSettings.DestinationArn)
+		}
+		// Settings for */* apply to every method of the stage
+		if settings, ok := stage.MethodSettings["*/*"]; ok {
+			metadata["throttling_burst_limit"] = settings.ThrottlingBurstLimit
+			metadata["throttling_rate_limit"] = settings.ThrottlingRateLimit
+			metadata["metrics_enabled"] = settings.MetricsEnabled
+			metadata["logging_level"] = aws.ToString(settings.LoggingLevel)
+		}
+		stages = append(stages, metadata)
+	}
+	return stages
+}
+
+func getRestApiDeployments(svc *apigateway.Client, apiID string) (int, []map[string]interface{}) {
+	var deployments []map[string]interface{}
+	paginator := apigateway.NewGetDeploymentsPaginator(svc, &apigateway.GetDeploymentsInput{
+		RestApiId: aws.String(apiID),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			break
+		}
+		for _, deployment := range page.Items {
+			deployments = append(deployments, map[string]interface{}{
+				"deployment_id": aws.ToString(deployment.Id),
+				"description":   aws.ToString(deployment.Description),
+				"created_date":  aws.ToTime(deployment.CreatedDate),
+			})
+		}
+	}
+	return len(deployments), recentDeployments(deployments)
+}
+
+// getUsagePlans returns the usage plans of every REST API stage, keyed by API ID
+func getUsagePlans(svc *apigateway.Client) map[string][]map[string]interface{} {
+	plans := make(map[string][]map[string]interface{})
+	paginator := apigateway.NewGetUsagePlansPaginator(svc, &apigateway.GetUsagePlansInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			log.Println("Error listing API Gateway usage plans:", err)
+			return plans
+		}
+
+		for _, plan := range page.Items {
+			for _, apiStage := range plan.ApiStages {
+				metadata := map[string]interface{}{
+					"usage_plan_id": aws.ToString(plan.Id),
+					"name":          aws.ToString(plan.Name),
+					"stage":         aws.ToString(apiStage.Stage),
+				}
+				if plan.Throttle != nil {
+					metadata["throttle_burst_limit"] = plan.Throttle.BurstLimit
+					metadata["throttle_rate_limit"] = plan.Throttle.RateLimit
+				}
+				if plan.Quota != nil {
+					metadata["quota_limit"] = plan.Quota.Limit
+					metadata["quota_period"] = plan.Quota.Period
+				}
+
+				apiID := aws.ToString(apiStage.ApiId)
+				plans[apiID] = append(plans[apiID], metadata)
+			}
+		}
+	}
+	return plans
+}
+
+// getApiDomainMappings returns the custom domains of every API, keyed by API ID.
+// API mappings cover REST APIs as well as HTTP and WebSocket APIs.
+func getApiDomainMappings(svc *apigatewayv2.Client) map[string][]map[string]interface{} {
+	mappings := make(map[string][]map[string]interface{})
+
+	var domains []apiv2Types.DomainName
+	input := &apigatewayv2.GetDomainNamesInput{}
+	for {
+		result, err := svc.GetDomainNames(context.TODO(), input)
+		if err != nil {
+			log.Println("Error listing API Gateway custom domains:", err)
+			return mappings
+		}
+		domains = append(domains, result.Items...)
+		if result.NextToken == nil {
+			break
+		}
+		input.NextToken = result.NextToken
+	}
+
+	for _, domain := range domains {
+		domainName := aws.ToString(domain.DomainName)
+		input := &apigatewayv2.GetApiMappingsInput{DomainName: domain.DomainName}
+		for {
+			result, err := svc.GetApiMappings(context.TODO(), input)
+			if err != nil {
+				break
+			}
+			for _, mapping := range result.Items {
+				apiID := aws.ToString(mapping.ApiId)
+				mappings[apiID] = append(mappings[apiID], map[string]interface{}{
+					"domain_name": domainName,
+					"base_path":   aws.ToString(mapping.ApiMappingKey),
+					"stage":       aws.ToString(mapping.Stage),
+				})
+			}
+			if result.NextToken == nil {
+				break
+			}
+			input.NextToken = result.NextToken
+		}
+	}
+	return mappings
+}
+
+// listApiGatewayV2Apis retrieves HTTP and WebSocket APIs
+func listApiGatewayV2Apis(svc *apigatewayv2.Client, domains map[string][]map[string]interface{}) ([]ResourceMetadata, error) {
+	var resources []ResourceMetadata
+	input := &apigatewayv2.GetApisInput{}
+	for {
+		result, err := svc.GetApis(context.TODO(), input)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, api := range result.Items {
+			id := aws.ToString(api.ApiId)
+			metadata := apiV2Metadata(api, domains)
+			metadata["stages"] = getApiV2Stages(svc, id)
+			count, deployments := getApiV2Deployments(svc, id)
+			metadata["deployment_count"] = count
+			metadata["recent_deployments"] = deployments
+
+			resources = append(resources, ResourceMetadata{
+				ResourceID: id,
+				MetaData:   metadata,
+			})
+		}
+
+		if result.NextToken == nil {
+			break
+		}
+		input.NextToken = result.NextToken
+	}
+	return resources, nil
+}
+
+// apiV2Metadata maps an HTTP or WebSocket API with its custom domains, keyed by API ID
+func apiV2Metadata(api apiv2Types.Api, domains map[string][]map[string]interface{}) map[string]interface{} {
+	id := aws.ToString(api.ApiId)
+	resourceType := "http_api"
+	if api.ProtocolType == apiv2Types.ProtocolTypeWebsocket {
+		resourceType = "websocket_api"
+	}
+
+	metadata := map[string]interface{}{
+		"resource_type":  resourceType,
+		"name":           aws.ToString(api.Name),
+		"description":    aws.ToString(api.Description),
+		"protocol_type":  api.ProtocolType,
+		"api_endpoint":   aws.ToString(api.ApiEndpoint),
+		"created_date":   api.CreatedDate,
+		"tags":           api.Tags,
+		"custom_domains": domains[id],
+	}
+	setCloudWatchRef(metadata, "AWS/ApiGateway", map[string]string{"ApiId": id})
+	return metadata
+}
+
+func getApiV2Stages(svc *apigatewayv2.Client, apiID string) []map[string]interface{} {
+	var stages []map[string]interface{}
+	input := &apigatewayv2.GetStagesInput{ApiId: aws.String(apiID)}
+	for {
+		result, err := svc.GetStages(context.TODO(), input)
+		if err != nil {
+			return stages
+		}
+
+		for _, stage := range result.Items {
+			stageName := aws.ToString(stage.StageName)
+			metadata := map[string]interface{}{
+				"stage_name":            stageName,
+				"deployment_id":         aws.ToString(stage.DeploymentId),
+				"auto_deploy":           aws.ToBool(stage.AutoDeploy),
+				"last_updated":          stage.LastUpdatedDate,
+				"cloudwatch_dimensions": map[string]string{"ApiId": apiID, "Stage": stageName},
+			}
+			if stage.AccessLogSettings != nil {
+				metadata["access_log_destination"] = aws.ToString(stage.AccessLogSettings.DestinationArn)
+			}
+			if settings := stage.DefaultRouteSettings; settings != nil {
+				metadata["throttling_burst_limit"] = aws.ToInt32(settings.ThrottlingBurstLimit)
+				metadata["throttling_rate_limit"] = aws.ToFloat64(settings.ThrottlingRateLimit)
+				metadata["metrics_enabled"] = aws.ToBool(settings.DetailedMetricsEnabled)
+				metadata["logging_level"] = settings.LoggingLevel
+			}
+			stages = append(stages, metadata)
+		}
+
+		if result.NextToken == nil {
+			break
+		}
+		input.NextToken = result.NextToken
+	}
+	return stages
+}
+
+func getApiV2Deployments(svc *apigatewayv2.Client, apiID string) (int, []map[string]interface{}) {
+	var deployments []map[string]interface{}
+	input := &apigatewayv2.GetDeploymentsInput{ApiId: aws.String(apiID)}
+	for {
+		result, err := svc.GetDeployments(context.TODO(), input)
+		if err != nil {
+			break
+		}
+
+		for _, deployment := range result.Items {
+			deployments = append(deployments, map[string]interface{}{
+				"deployment_id": aws.ToString(deployment.DeploymentId),
+				"description":   aws.ToString(deployment.Description),
+				"status":        deployment.DeploymentStatus,
+				"auto_deployed": aws.ToBool(deployment.AutoDeployed),
+				"created_date":  aws.ToTime(deployment.CreatedDate),
+			})
+		}
+
+		if result.NextToken == nil {
+			break
+		}
+		input.NextToken = result.NextToken
+	}
+	return len(deployments), recentDeployments(deployments)
+}
+
+// recentDeployments returns the most recent deployments, newest first
+func recentDeployments(deployments []map[string]interface{}) []map[string]interface{} {
+	sort.SliceStable(deployments, func(i, j int) bool {
+		return deployments[i]["created_date"].(time.Time).After(deployments[j]["created_date"].(time.Time))
+	})
+	if len(deployments) > maxApiDeployments {
+		deployments = deployments[:maxApiDeployments]
+	}
+	return deployments
+}
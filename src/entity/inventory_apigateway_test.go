@@ -0,0 +1,139 @@
+package entity
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	apiTypes "github.com/aws/aws-sdk-go-v2/service/apigateway/types"
+	apiv2Types "github.com/aws/aws-sdk-go-v2/service/apigatewayv2/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecentDeployments(t *testing.T) {
+	start := time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+	var deployments []map[string]interface{}
+	for i := 0; i < maxApiDeployments+5; i++ {
+		deployments = append(deployments, map[string]interface{}{
+			"deployment_id": fmt.Sprintf("d%d", i),
+			"created_date":  start.Add(time.Duration(i) * time.Hour),
+		})
+	}
+	// Deployments are not returned in order
+	deployments[0], deployments[14] = deployments[14], deployments[0]
+
+	recent := recentDeployments(deployments)
+	assert.Len(t, recent, maxApiDeployments)
+	assert.Equal(t, "d14", recent[0]["deployment_id"])
+	assert.Equal(t, "d13", recent[1]["deployment_id"])
+	assert.Equal(t, "d5", recent[maxApiDeployments-1]["deployment_id"])
+
+	assert.Empty(t, recentDeployments(nil))
+}
+
+func TestApiGatewayMetadata(t *testing.T) {
+	// API mappings of custom domains cover both REST and v2 APIs
+	domains := map[string][]map[string]interface{}{
+		"rest123": {{"domain_name": "api.example.com", "base_path": "v1", "stage": "prod"}},
+		"http456": {{"domain_name": "api.example.com", "base_path": "v2", "stage": "$default"}},
+	}
+	usagePlans := map[string][]map[string]interface{}{
+		"rest123": {{"usage_plan_id": "plan1", "name": "partners", "stage": "prod"}},
+	}
+
+	rest := restApiMetadata(apiTypes.RestApi{
+		Id:   aws.String("rest123"),
+		Name: aws.String("orders"),
+		EndpointConfiguration: &apiTypes.EndpointConfiguration{
+			Types: []apiTypes.EndpointType{apiTypes.EndpointTypeRegional},
+		},
+	}, usagePlans, domains)
+	assert.Equal(t, "rest_api", rest["resource_type"])
+	assert.Equal(t, []string{"REGIONAL"}, rest["endpoint_types"])
+	assert.Equal(t, usagePlans["rest123"], rest["usage_plans"])
+	assert.Equal(t, domains["rest123"], rest["custom_domains"])
+	assert.Equal(t, map[string]string{"ApiName": "orders"}, rest["cloudwatch_dimensions"])
+
+	httpAPI := apiV2Metadata(apiv2Types.Api{
+		ApiId:        aws.String("http456"),
+		Name:         aws.String("orders"),
+		ProtocolType: apiv2Types.ProtocolTypeHttp,
+		ApiEndpoint:  aws.String("https://http456.execute-api.eu-west-1.amazonaws.com"),
+	}, domains)
+	assert.Equal(t, "http_api", httpAPI["resource_type"])
+	assert.Equal(t, domains["http456"], httpAPI["custom_domains"])
+	assert.Equal(t, map[string]string{"ApiId": "http456"}, httpAPI["cloudwatch_dimensions"])
+	assert.NotContains(t, httpAPI, "usage_plans")
+
+	websocket := apiV2Metadata(apiv2Types.Api{
+		ApiId:        aws.String("ws789"),
+		ProtocolType: apiv2Types.ProtocolTypeWebsocket,
+	}, domains)
+	assert.Equal(t, "websocket_api", websocket["resource_type"])
+	assert.Nil(t, websocket["custom_domains"])
+}
+
+func TestListApiGateways(t *testing.T) {
+	v2Denied := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if v2Denied && strings.HasPrefix(r.URL.Path, "/v2/apis") {
+			w.Header().Set("X-Amzn-Errortype", "AccessDeniedException")
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"message":"denied"}`)
+			return
+		}
+		switch r.URL.Path {
+		case "/restapis":
+			fmt.Fprint(w, `{"item":[{"id":"rest123","name":"orders"}]}`)
+		case "/restapis/rest123/deployments":
+			fmt.Fprint(w, `{"item":[{"id":"d1","createdDate":1788220800},{"id":"d2","createdDate":1788307200}]}`)
+		case "/v2/domainnames":
+			fmt.Fprint(w, `{"items":[{"domainName":"api.example.com"}]}`)
+		case "/v2/domainnames/api.example.com/apimappings":
+			fmt.Fprint(w, `{"items":[{"apiId":"rest123","apiMappingKey":"v1","stage":"prod"},{"apiId":"http456","apiMappingKey":"v2","stage":"$default"}]}`)
+		case "/v2/apis":
+			fmt.Fprint(w, `{"items":[{"apiId":"http456","name":"orders","protocolType":"HTTP"}]}`)
+		default:
+			fmt.Fprint(w, `{}`)
+		}
+	}))
+	defer server.Close()
+
+	cfg := aws.Config{
+		Region:       "eu-west-1",
+		Credentials:  credentials.NewStaticCredentialsProvider("key", "secret", ""),
+		BaseEndpoint: aws.String(server.URL),
+	}
+	resources, err := listApiGateways(cfg)
+	assert.NoError(t, err)
+	assert.Len(t, resources, 2)
+
+	rest := resources[0]
+	assert.Equal(t, "rest123", rest.ResourceID)
+	assert.Equal(t, "rest_api", rest.MetaData["resource_type"])
+	assert.Equal(t, 2, rest.MetaData["deployment_count"])
+	deployments := rest.MetaData["recent_deployments"].([]map[string]interface{})
+	assert.Equal(t, "d2", deployments[0]["deployment_id"])
+	assert.Equal(t, "v1", rest.MetaData["custom_domains"].([]map[string]interface{})[0]["base_path"])
+
+	httpAPI := resources[1]
+	assert.Equal(t, "http456", httpAPI.ResourceID)
+	assert.Equal(t, "http_api", httpAPI.MetaData["resource_type"])
+	assert.Equal(t, "v2", httpAPI.MetaData["custom_domains"].([]map[string]interface{})[0]["base_path"])
+
+	// REST APIs are kept when v2 APIs cannot be listed
+	v2Denied = true
+	resources, err = listApiGateways(cfg)
+	var partial *partialResourcesError
+	assert.ErrorAs(t, err, &partial)
+	assert.Equal(t, "apigateway_v2", partial.Step)
+	assert.True(t, isAuthError(err))
+	assert.Len(t, resources, 1)
+	assert.Equal(t, "rest123", resources[0].ResourceID)
+}
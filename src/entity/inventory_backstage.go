@@ -0,0 +1,280 @@
+package entity
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Backstage entity names are at most 63 characters of letters, digits and -_. separators
+const maxBackstageNameLength = 63
+
+var backstageInvalidNameChars = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+
+// Entity names separate alphanumerics with single dashes, underscores or dots
+var backstageRepeatedSeparators = regexp.MustCompile(`[_.-]{2,}`)
+
+// BackstageEntity is a Resource entity of the Backstage software catalog
+type BackstageEntity struct {
+	APIVersion string                `yaml:"apiVersion"`
+	Kind       string                `yaml:"kind"`
+	Metadata   BackstageMetadata     `yaml:"metadata"`
+	Spec       BackstageResourceSpec `yaml:"spec"`
+}
+
+type BackstageMetadata struct {
+	Name        string            `yaml:"name"`
+	Title       string            `yaml:"title,omitempty"`
+	Annotations map[string]string `yaml:"annotations,omitempty"`
+	Tags        []string          `yaml:"tags,omitempty"`
+}
+
+type BackstageResourceSpec struct {
+	Type      string   `yaml:"type"`
+	Owner     string   `yaml:"owner"`
+	DependsOn []string `yaml:"dependsOn,omitempty"`
+}
+
+// writeBackstageCatalog writes every inventory resource as a Resource entity to <out>/catalog-info.yaml
+func writeBackstageCatalog(services []ServiceMetadata, out string, options exportOptions) error {
+	entities := backstageEntities(services, options)
+
+	if err := os.MkdirAll(out, os.ModePerm); err != nil {
+		return err
+	}
+	file, err := os.Create(filepath.Join(out, "catalog-info.yaml"))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	encoder := yaml.NewEncoder(file)
+	encoder.SetIndent(2)
+	for _, entity := range entities {
+		if err := encoder.Encode(entity); err != nil {
+			return err
+		}
+	}
+	return encoder.Close()
+}
+
+// backstageEntities maps inventory resources to Resource entities. Owners come from the first owner tag set
+// on the resource, dependencies from the VPC and load balancer targets the collectors record.
+func backstageEntities(services []ServiceMetadata, options exportOptions) []BackstageEntity {
+	// Resource IDs are unique per service, entity names must be unique in the catalog
+	refs := make(map[string]string)
+	used := make(map[string]bool)
+	names := make([][]string, len(services))
+	for i, service := range services {
+		_, code := backstageServiceCode(service)
+		for _, resource := range service.Resources {
+			if !isBackstageResource(resource) {
+				names[i] = append(names[i], "")
+				continue
+			}
+			name := backstageName(code, resource.ResourceID, "")
+			for n := 2; used[name]; n++ {
+				name = backstageName(code, resource.ResourceID, "-"+strconv.Itoa(n))
+			}
+			used[name] = true
+			names[i] = append(names[i], name)
+			if _, ok := refs[resource.ResourceID]; !ok {
+				refs[resource.ResourceID] = "resource:" + name
+			}
+		}
+	}
+
+	var entities []BackstageEntity
+	for i, service := range services {
+		source, code := backstageServiceCode(service)
+		for j, resource := range service.Resources {
+			if !isBackstageResource(resource) {
+				continue
+			}
+			entityType := code
+			if resourceType := metadataString(resource.MetaData, "resource_type"); resourceType != "" {
+				entityType += "-" + strings.ReplaceAll(resourceType, "_", "-")
+			}
+
+			var dependsOn []string
+			for _, id := range resourceDependencies(resource.MetaData) {
+				if ref, ok := refs[id]; ok && id != resource.ResourceID {
+					dependsOn = append(dependsOn, ref)
+				}
+			}
+
+			entities = append(entities, BackstageEntity{
+				APIVersion: "backstage.io/v1alpha1",
+				Kind:       "Resource",
+				Metadata: BackstageMetadata{
+					Name:  names[i][j],
+					Title: resource.ResourceID,
+					Annotations: map[string]string{
+						"autoptic.io/service-name": service.ServiceName,
+						"autoptic.io/resource-id":  resource.ResourceID,
+						"autoptic.io/account-id":   metadataString(service.MetaData, "account_id"),
+						"autoptic.io/region":       metadataString(service.MetaData, "region"),
+					},
+					Tags: []string{source, code},
+				},
+				Spec: BackstageResourceSpec{
+					Type:      entityType,
+					Owner:     resourceOwner(resource.MetaData, options.OwnerTags, options.DefaultOwner),
+					DependsOn: dependsOn,
+				},
+			})
+		}
+	}
+	return entities
+}
+
+// backstageServiceCode returns the source and the code of a service used in entity names, types and tags.
+// Services without an AWS service code, such as Prometheus jobs and Sentry projects, use a slug of their name.
+func backstageServiceCode(service ServiceMetadata) (string, string) {
+	source := metadataString(service.MetaData, "source")
+	if source == "" {
+		source = "aws"
+	}
+	code, _ := mapServiceNameToCode(service.ServiceName)
+	if code == "" {
+		// Tags are at most 63 characters
+		code = slugify(service.ServiceName)
+		if len(code) > 63 {
+			code = strings.TrimRight(code[:63], "-")
+		}
+	}
+	if code == "" {
+		code = source
+	}
+	return source, code
+}
+
+// isBackstageResource skips the metric catalog and sending quota entries, which describe the account
+// rather than a resource
+func isBackstageResource(resource ResourceMetadata) bool {
+	switch metadataString(resource.MetaData, "resource_type") {
+	case "metric_namespace", "sending_quota":
+		return false
+	}
+	return true
+}
+
+// backstageName builds an entity name from the service code and the resource ID, or the resource part of an ARN.
+// The suffix tells apart resources whose names collide once truncated.
+func backstageName(code, id, suffix string) string {
+	if strings.HasPrefix(id, "arn:") {
+		if parts := strings.SplitN(id, ":", 6); len(parts) == 6 {
+			id = parts[5]
+		}
+	}
+	name := backstageInvalidNameChars.ReplaceAllString(code+"-"+id, "-")
+	name = backstageRepeatedSeparators.ReplaceAllString(name, "-")
+	if len(name) > maxBackstageNameLength-len(suffix) {
+		name = name[:maxBackstageNameLength-len(suffix)]
+	}
+	return strings.Trim(name, "-_.") + suffix
+}
+
+// resourceOwner returns the entity reference of the first owner tag found on the resource
+func resourceOwner(metadata map[string]interface{}, ownerTags []string, defaultOwner string) string {
+	tags := resourceTags(metadata)
+	for _, key := range ownerTags {
+		for tagKey, value := range tags {
+			if strings.EqualFold(tagKey, key) && value != "" {
+				return backstageOwnerRef(value)
+			}
+		}
+	}
+	return backstageOwnerRef(defaultOwner)
+}
+
+// backstageOwnerRef turns an owner into an entity reference, [kind:][namespace/]name. Tags hold team names like
+// "Payments Team", which are lowercased and sanitized like entity names and refer to a group unless they name a kind.
+func backstageOwnerRef(owner string) string {
+	kind := "group"
+	if i := strings.Index(owner, ":"); i > 0 {
+		kind, owner = strings.ToLower(owner[:i]), owner[i+1:]
+	}
+	parts := strings.Split(owner, "/")
+	for i, part := range parts {
+		part = backstageInvalidNameChars.ReplaceAllString(strings.ToLower(strings.TrimSpace(part)), "-")
+		part = strings.Trim(backstageRepeatedSeparators.ReplaceAllString(part, "-"), "-_.")
+		if len(part) > maxBackstageNameLength {
+			part = strings.Trim(part[:maxBackstageNameLength], "-_.")
+		}
+		parts[i] = part
+	}
+	return kind + ":" + strings.Join(parts, "/")
+}
+
+// resourceTags returns the tags of a resource, recorded by most collectors as a map and by load balancers
+// as tag descriptions
+func resourceTags(metadata map[string]interface{}) map[string]string {
+	tags := make(map[string]string)
+	if metadata["tags"] == nil {
+		return tags
+	}
+	if err := decodeMetadata(metadata["tags"], &tags); err == nil {
+		return tags
+	}
+
+	var descriptions []struct {
+		Tags []struct {
+			Key   string
+			Value string
+		}
+	}
+	if err := decodeMetadata(metadata["tags"], &descriptions); err == nil {
+		for _, description := range descriptions {
+			for _, tag := range description.Tags {
+				tags[tag.Key] = tag.Value
+			}
+		}
+	}
+	return tags
+}
+
+// resourceDependencies returns the IDs of the VPC a resource runs in, and of the targets and VPCs of
+// load balancer target groups
+func resourceDependencies(metadata map[string]interface{}) []string {
+	ids := make(map[string]bool)
+	if vpcID := metadataString(metadata, "vpc_id"); vpcID != "" {
+		ids[vpcID] = true
+	}
+
+	var targetGroups []struct {
+		VpcId string
+	}
+	if err := decodeMetadata(metadata["target_groups"], &targetGroups); err == nil {
+		for _, targetGroup := range targetGroups {
+			if targetGroup.VpcId != "" {
+				ids[targetGroup.VpcId] = true
+			}
+		}
+	}
+
+	for key, value := range metadata {
+		if !strings.HasPrefix(key, "instances_") {
+			continue
+		}
+		var targets []struct {
+			Target struct {
+				Id string
+			}
+		}
+		if err := decodeMetadata(value, &targets); err != nil {
+			continue
+		}
+		for _, target := range targets {
+			if target.Target.Id != "" {
+				ids[target.Target.Id] = true
+			}
+		}
+	}
+
+	return sortedKeys(ids)
+}
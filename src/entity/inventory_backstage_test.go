@@ -0,0 +1,74 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackstageEntities(t *testing.T) {
+	services := []ServiceMetadata{
+		{ServiceName: "Amazon Elastic Compute Cloud - Compute", MetaData: map[string]interface{}{"account_id": "123", "region": "eu-west-1"},
+			Resources: []ResourceMetadata{
+				{ResourceID: "i-1", MetaData: map[string]interface{}{
+					"vpc_id": "vpc-1",
+					"tags":   map[string]string{"Team": "payments"},
+				}},
+			}},
+		{ServiceName: "Amazon Elastic Load Balancing", MetaData: map[string]interface{}{},
+			Resources: []ResourceMetadata{
+				{ResourceID: "arn:aws:elasticloadbalancing:eu-west-1:123:loadbalancer/app/web/abc", MetaData: map[string]interface{}{
+					"tags":                 []interface{}{map[string]interface{}{"Tags": []interface{}{map[string]interface{}{"Key": "owner", "Value": "platform"}}}},
+					"target_groups":        []interface{}{map[string]interface{}{"VpcId": "vpc-1"}},
+					"instances_arn:tg/web": []interface{}{map[string]interface{}{"Target": map[string]interface{}{"Id": "i-1"}}},
+				}},
+			}},
+		{ServiceName: "Amazon Virtual Private Cloud", MetaData: map[string]interface{}{},
+			Resources: []ResourceMetadata{{ResourceID: "vpc-1", MetaData: map[string]interface{}{}}}},
+		{ServiceName: "AmazonCloudWatch", MetaData: map[string]interface{}{},
+			Resources: []ResourceMetadata{{ResourceID: "AWS/EC2", MetaData: map[string]interface{}{"resource_type": "metric_namespace"}}}},
+	}
+
+	entities := backstageEntities(services, exportOptions{OwnerTags: []string{"owner", "team"}, DefaultOwner: "unknown"})
+
+	assert.Len(t, entities, 3)
+	assert.Equal(t, "ec2-i-1", entities[0].Metadata.Name)
+	assert.Equal(t, "group:payments", entities[0].Spec.Owner)
+	assert.Equal(t, []string{"resource:vpc-vpc-1"}, entities[0].Spec.DependsOn)
+	assert.Equal(t, "elb-loadbalancer-app-web-abc", entities[1].Metadata.Name)
+	assert.Equal(t, "group:platform", entities[1].Spec.Owner)
+	assert.Equal(t, []string{"resource:ec2-i-1", "resource:vpc-vpc-1"}, entities[1].Spec.DependsOn)
+	assert.Equal(t, "group:unknown", entities[2].Spec.Owner)
+}
+
+func TestBackstageOwnerRef(t *testing.T) {
+	assert.Equal(t, "group:payments-team", backstageOwnerRef("Payments Team"))
+	assert.Equal(t, "group:data_platform", backstageOwnerRef("data_platform"))
+	assert.Equal(t, "user:default/jane.doe", backstageOwnerRef("User:default/Jane.Doe"))
+	assert.Equal(t, "group:ops", backstageOwnerRef("group:ops"))
+}
+
+func TestBackstageName(t *testing.T) {
+	long := "a-very-long-bucket-name-that-goes-on-and-on-past-the-backstage-limit"
+	assert.Len(t, backstageName("s3", long, ""), maxBackstageNameLength)
+	assert.NotEqual(t, backstageName("s3", long, ""), backstageName("s3", long, "-2"))
+	assert.Equal(t, "s3-my_bucket.logs", backstageName("s3", "my_bucket.logs", ""))
+}
+
+func TestBackstageEntitiesOtherSources(t *testing.T) {
+	services := []ServiceMetadata{
+		{ServiceName: "node", MetaData: map[string]interface{}{"source": "prometheus"},
+			Resources: []ResourceMetadata{{ResourceID: "node-a:9100", MetaData: map[string]interface{}{"resource_type": "target"}}}},
+		{ServiceName: "Checkout API", MetaData: map[string]interface{}{"source": "sentry"},
+			Resources: []ResourceMetadata{{ResourceID: "-checkout", MetaData: map[string]interface{}{}}}},
+	}
+
+	entities := backstageEntities(services, exportOptions{DefaultOwner: "unknown"})
+
+	assert.Equal(t, "node-node-a-9100", entities[0].Metadata.Name)
+	assert.Equal(t, []string{"prometheus", "node"}, entities[0].Metadata.Tags)
+	assert.Equal(t, "node-target", entities[0].Spec.Type)
+	assert.Equal(t, "checkout-api-checkout", entities[1].Metadata.Name)
+	assert.Equal(t, []string{"sentry", "checkout-api"}, entities[1].Metadata.Tags)
+	assert.Equal(t, "bucket", backstageName("", "-bucket", ""))
+}
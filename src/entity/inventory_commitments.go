@@ -0,0 +1,369 @@
+package entity
+
+import (
+	"context"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/costexplorer"
+	costTypes "github.com/aws/aws-sdk-go-v2/service/costexplorer/types"
+	"github.com/spf13/cobra"
+)
+
+// Services whose on-demand spend is measured against Savings Plans, which also count usage covered by
+// Reserved Instances as covered
+var savingsPlansServices = []string{"Amazon Elastic Compute Cloud - Compute", "AWS Lambda"}
+
+// Services whose on-demand spend is measured against Reserved Instances only
+var reservationServices = []string{"Amazon Relational Database Service"}
+
+// CommitmentReport is the utilization of the Savings Plans and Reserved Instances of an account and the
+// on-demand spend they leave uncovered
+type CommitmentReport struct {
+	Start             string                         `json:"start"`
+	End               string                         `json:"end"`
+	SavingsPlans      *SavingsPlansUtilizationReport `json:"savings_plans,omitempty"`
+	ReservedInstances *ReservationUtilizationReport  `json:"reserved_instances,omitempty"`
+	UncoveredTotal    float64                        `json:"uncovered_total"`
+	Uncovered         []UncoveredSpend               `json:"uncovered"`
+}
+
+type SavingsPlansUtilizationReport struct {
+	UtilizationPercentage float64 `json:"utilization_percentage"`
+	TotalCommitment       float64 `json:"total_commitment"`
+	UnusedCommitment      float64 `json:"unused_commitment"`
+	NetSavings            float64 `json:"net_savings"`
+}
+
+type ReservationUtilizationReport struct {
+	UtilizationPercentage float64 `json:"utilization_percentage"`
+	PurchasedHours        float64 `json:"purchased_hours"`
+	UnusedHours           float64 `json:"unused_hours"`
+	NetRISavings          float64 `json:"net_ri_savings"`
+}
+
+// UncoveredSpend is the on-demand spend of an instance family in a region, with the inventory resources
+// running in it. Lambda has no instance families, its spend is per region.
+type UncoveredSpend struct {
+	ServiceName        string   `json:"service_name"`
+	InstanceFamily     string   `json:"instance_family,omitempty"`
+	Region             string   `json:"region"`
+	Commitment         string   `json:"commitment"`
+	OnDemandCost       float64  `json:"on_demand_cost"`
+	CoveragePercentage float64  `json:"coverage_percentage"`
+	ResourceIDs        []string `json:"resource_ids"`
+}
+
+func commitmentsInventoryCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "commitments",
+		Short: "Report Savings Plans and Reserved Instance utilization and the on-demand spend they leave uncovered",
+		Run: func(cmd *cobra.Command, args []string) {
+			in, _ := cmd.Flags().GetString("in")
+			out, _ := cmd.Flags().GetString("out")
+			days, _ := cmd.Flags().GetInt("days")
+			log.Printf("Creating commitment report from %s at %s\n", in, out)
+			err := makeCommitmentReport(in, out, days, awsConfigOptionsFromFlags(cmd))
+			if err != nil {
+				log.Printf("Error creating commitment report: %v\n", err)
+			}
+		},
+	}
+	cmd.Flags().String("in", "", "Input path of the inventory file to match the uncovered spend with")
+	cmd.Flags().String("out", "", "Output path for the commitment report")
+	cmd.Flags().Int("days", 30, "Number of days of Cost Explorer data to report on")
+	addAWSConfigFlags(cmd)
+
+	cmd.MarkFlagRequired("in")
+	cmd.MarkFlagRequired("out")
+	cmd.MarkFlagFilename("in")
+	cmd.MarkFlagFilename("out")
+	return cmd
+}
+
+func makeCommitmentReport(in, out string, days int, awsOptions awsConfigOptions) error {
+	services, err := readInventoryFile(in)
+	if err != nil {
+		return err
+	}
+	cfg, err := loadAWSConfig(awsOptions)
+	if err != nil {
+		return err
+	}
+
+	endTime := time.Now()
+	period := &costTypes.DateInterval{
+		Start: aws.String(endTime.AddDate(0, 0, -days).Format("2006-01-02")),
+		End:   aws.String(endTime.Format("2006-01-02")),
+	}
+	report := CommitmentReport{Start: aws.ToString(period.Start), End: aws.ToString(period.End)}
+
+	// Accounts without Savings Plans or Reserved Instances get an error instead of zero utilization
+	report.SavingsPlans, err = getSavingsPlansUtilization(cfg, period)
+	if err != nil {
+		log.Println("No Savings Plans utilization:", err)
+	}
+	report.ReservedInstances, err = getReservationUtilization(cfg, period)
+	if err != nil {
+		log.Println("No Reserved Instance utilization:", err)
+	}
+
+	for _, serviceName := range savingsPlansServices {
+		uncovered, err := getSavingsPlansUncovered(cfg, period, serviceName)
+		if err != nil {
+			return err
+		}
+		report.Uncovered = append(report.Uncovered, uncovered...)
+	}
+	for _, serviceName := range reservationServices {
+		uncovered, err := getReservationUncovered(cfg, period, serviceName)
+		if err != nil {
+			return err
+		}
+		report.Uncovered = append(report.Uncovered, uncovered...)
+	}
+
+	matchUncoveredResources(report.Uncovered, services)
+	sort.SliceStable(report.Uncovered, func(i, j int) bool {
+		return report.Uncovered[i].OnDemandCost > report.Uncovered[j].OnDemandCost
+	})
+	for _, spend := range report.Uncovered {
+		report.UncoveredTotal += spend.OnDemandCost
+	}
+
+	log.Printf("Found %.2f of uncovered on-demand spend in %d instance families and regions\n",
+		report.UncoveredTotal, len(report.Uncovered))
+	return writeToJsonFile(report, out)
+}
+
+func getSavingsPlansUtilization(cfg aws.Config, period *costTypes.DateInterval) (*SavingsPlansUtilizationReport, error) {
+	svc := costexplorer.NewFromConfig(cfg)
+	resp, err := svc.GetSavingsPlansUtilization(context.TODO(), &costexplorer.GetSavingsPlansUtilizationInput{
+		TimePeriod: period,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if resp.Total == nil || resp.Total.Utilization == nil {
+		return nil, nil
+	}
+
+	utilization := &SavingsPlansUtilizationReport{
+		UtilizationPercentage: parseCostAmount(resp.Total.Utilization.UtilizationPercentage),
+		TotalCommitment:       parseCostAmount(resp.Total.Utilization.TotalCommitment),
+		UnusedCommitment:      parseCostAmount(resp.Total.Utilization.UnusedCommitment),
+	}
+	if resp.Total.Savings != nil {
+		utilization.NetSavings = parseCostAmount(resp.Total.Savings.NetSavings)
+	}
+	return utilization, nil
+}
+
+func getReservationUtilization(cfg aws.Config, period *costTypes.DateInterval) (*ReservationUtilizationReport, error) {
+	svc := costexplorer.NewFromConfig(cfg)
+	resp, err := svc.GetReservationUtilization(context.TODO(), &costexplorer.GetReservationUtilizationInput{
+		TimePeriod: period,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if resp.Total == nil {
+		return nil, nil
+	}
+
+	return &ReservationUtilizationReport{
+		UtilizationPercentage: parseCostAmount(resp.Total.UtilizationPercentage),
+		PurchasedHours:        parseCostAmount(resp.Total.PurchasedHours),
+		UnusedHours:           parseCostAmount(resp.Total.UnusedHours),
+		NetRISavings:          parseCostAmount(resp.Total.NetRISavings),
+	}, nil
+}
+
+// getSavingsPlansUncovered returns the on-demand spend of a service per instance family and region
+func getSavingsPlansUncovered(cfg aws.Config, period *costTypes.DateInterval, serviceName string) ([]UncoveredSpend, error) {
+	svc := costexplorer.NewFromConfig(cfg)
+	input := &costexplorer.GetSavingsPlansCoverageInput{
+		TimePeriod: period,
+		Filter: &costTypes.Expression{
+			Dimensions: &costTypes.DimensionValues{
+				Key:    costTypes.DimensionService,
+				Values: []string{serviceName},
+			},
+		},
+		GroupBy: []costTypes.GroupDefinition{
+			{Type: costTypes.GroupDefinitionTypeDimension, Key: aws.String("INSTANCE_FAMILY")},
+			{Type: costTypes.GroupDefinitionTypeDimension, Key: aws.String("REGION")},
+		},
+	}
+
+	type spendKey struct{ family, region string }
+	onDemand := make(map[spendKey]float64)
+	total := make(map[spendKey]float64)
+	for {
+		resp, err := svc.GetSavingsPlansCoverage(context.TODO(), input)
+		if err != nil {
+			return nil, err
+		}
+		for _, coverage := range resp.SavingsPlansCoverages {
+			if coverage.Coverage == nil {
+				continue
+			}
+			key := spendKey{
+				family: coverageAttribute(coverage.Attributes, "INSTANCE_FAMILY"),
+				region: coverageAttribute(coverage.Attributes, "REGION"),
+			}
+			onDemand[key] += parseCostAmount(coverage.Coverage.OnDemandCost)
+			total[key] += parseCostAmount(coverage.Coverage.TotalCost)
+		}
+		if resp.NextToken == nil {
+			break
+		}
+		input.NextToken = resp.NextToken
+	}
+
+	var uncovered []UncoveredSpend
+	for key, cost := range onDemand {
+		if cost <= 0 || total[key] <= 0 {
+			continue
+		}
+		uncovered = append(uncovered, UncoveredSpend{
+			ServiceName:        serviceName,
+			InstanceFamily:     key.family,
+			Region:             key.region,
+			Commitment:         "savings_plans",
+			OnDemandCost:       cost,
+			CoveragePercentage: (1 - cost/total[key]) * 100,
+		})
+	}
+	return uncovered, nil
+}
+
+// getReservationUncovered returns the on-demand spend of a service per instance family and region.
+// Reservation coverage is reported per instance type, which is summed up to its family.
+func getReservationUncovered(cfg aws.Config, period *costTypes.DateInterval, serviceName string) ([]UncoveredSpend, error) {
+	svc := costexplorer.NewFromConfig(cfg)
+	input := &costexplorer.GetReservationCoverageInput{
+		TimePeriod: period,
+		Filter: &costTypes.Expression{
+			Dimensions: &costTypes.DimensionValues{
+				Key:    costTypes.DimensionService,
+				Values: []string{serviceName},
+			},
+		},
+		GroupBy: []costTypes.GroupDefinition{
+			{Type: costTypes.GroupDefinitionTypeDimension, Key: aws.String("INSTANCE_TYPE")},
+			{Type: costTypes.GroupDefinitionTypeDimension, Key: aws.String("REGION")},
+		},
+	}
+
+	type spendKey struct{ family, region string }
+	onDemand := make(map[spendKey]float64)
+	onDemandHours := make(map[spendKey]float64)
+	runningHours := make(map[spendKey]float64)
+	for {
+		resp, err := svc.GetReservationCoverage(context.TODO(), input)
+		if err != nil {
+			return nil, err
+		}
+		for _, byTime := range resp.CoveragesByTime {
+			for _, group := range byTime.Groups {
+				if group.Coverage == nil {
+					continue
+				}
+				key := spendKey{
+					family: instanceFamily(coverageAttribute(group.Attributes, "INSTANCE_TYPE")),
+					region: coverageAttribute(group.Attributes, "REGION"),
+				}
+				if group.Coverage.CoverageCost != nil {
+					onDemand[key] += parseCostAmount(group.Coverage.CoverageCost.OnDemandCost)
+				}
+				if group.Coverage.CoverageHours != nil {
+					onDemandHours[key] += parseCostAmount(group.Coverage.CoverageHours.OnDemandHours)
+					runningHours[key] += parseCostAmount(group.Coverage.CoverageHours.TotalRunningHours)
+				}
+			}
+		}
+		if resp.NextPageToken == nil {
+			break
+		}
+		input.NextPageToken = resp.NextPageToken
+	}
+
+	var uncovered []UncoveredSpend
+	for key, cost := range onDemand {
+		if cost <= 0 {
+			continue
+		}
+		spend := UncoveredSpend{
+			ServiceName:    serviceName,
+			InstanceFamily: key.family,
+			Region:         key.region,
+			Commitment:     "reserved_instances",
+			OnDemandCost:   cost,
+		}
+		if runningHours[key] > 0 {
+			spend.CoveragePercentage = (1 - onDemandHours[key]/runningHours[key]) * 100
+		}
+		uncovered = append(uncovered, spend)
+	}
+	return uncovered, nil
+}
+
+// matchUncoveredResources lists the inventory resources of the service, instance family and region of each
+// uncovered spend. Stopped and terminated instances have no on-demand spend and are left out.
+func matchUncoveredResources(uncovered []UncoveredSpend, services []ServiceMetadata) {
+	for i := range uncovered {
+		spend := &uncovered[i]
+		spend.ResourceIDs = []string{}
+		for _, service := range services {
+			if service.ServiceName != spend.ServiceName || metadataString(service.MetaData, "region") != spend.Region {
+				continue
+			}
+			for _, resource := range service.Resources {
+				// EC2 instances have a state, RDS instances a status
+				state := metadataString(resource.MetaData, "state") + metadataString(resource.MetaData, "status")
+				if state == "stopped" || state == "terminated" {
+					continue
+				}
+				// Aurora cluster spend is the spend of its member instances, which are listed on their own
+				if metadataString(resource.MetaData, "resource_type") == "cluster" {
+					continue
+				}
+				// Lambda spend has no instance family and matches every function of its region
+				if spend.InstanceFamily != "" && instanceFamily(metadataString(resource.MetaData, "instance_type")) != spend.InstanceFamily {
+					continue
+				}
+				spend.ResourceIDs = append(spend.ResourceIDs, resource.ResourceID)
+			}
+		}
+		sort.Strings(spend.ResourceIDs)
+	}
+}
+
+// instanceFamily returns the family of an EC2 or RDS instance type, m5 for m5.large and db.r6g for db.r6g.xlarge
+func instanceFamily(instanceType string) string {
+	if i := strings.LastIndex(instanceType, "."); i > 0 {
+		return instanceType[:i]
+	}
+	return instanceType
+}
+
+// coverageAttribute looks up a group attribute, Cost Explorer returns INSTANCE_FAMILY as instanceFamily
+func coverageAttribute(attributes map[string]string, dimension string) string {
+	name := strings.ReplaceAll(dimension, "_", "")
+	for key, value := range attributes {
+		if strings.EqualFold(key, name) {
+			return value
+		}
+	}
+	return ""
+}
+
+func parseCostAmount(amount *string) float64 {
+	value, _ := strconv.ParseFloat(aws.ToString(amount), 64)
+	return value
+}
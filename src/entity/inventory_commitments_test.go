@@ -0,0 +1,44 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchUncoveredResources(t *testing.T) {
+	services := []ServiceMetadata{
+		{ServiceName: "Amazon Elastic Compute Cloud - Compute", MetaData: map[string]interface{}{"region": "eu-west-1"},
+			Resources: []ResourceMetadata{
+				{ResourceID: "i-2", MetaData: map[string]interface{}{"instance_type": "m5.large", "state": "running"}},
+				{ResourceID: "i-1", MetaData: map[string]interface{}{"instance_type": "m5.2xlarge", "state": "running"}},
+				{ResourceID: "i-3", MetaData: map[string]interface{}{"instance_type": "m5.large", "state": "stopped"}},
+				{ResourceID: "i-4", MetaData: map[string]interface{}{"instance_type": "c6g.large", "state": "running"}},
+			}},
+		{ServiceName: "Amazon Relational Database Service", MetaData: map[string]interface{}{"region": "eu-west-1"},
+			Resources: []ResourceMetadata{
+				{ResourceID: "orders", MetaData: map[string]interface{}{"instance_type": "db.r6g.xlarge", "status": "available"}},
+				{ResourceID: "orders-1", MetaData: map[string]interface{}{"instance_type": "db.r6g.large", "db_cluster_identifier": "orders-cluster"}},
+				{ResourceID: "orders-cluster", MetaData: map[string]interface{}{"resource_type": "cluster", "status": "available"}},
+				{ResourceID: "reports-cluster", MetaData: map[string]interface{}{"resource_type": "cluster", "instance_type": "db.r6g.large"}},
+				{ResourceID: "events", MetaData: map[string]interface{}{"engine": "mysql", "status": "available"}},
+			}},
+		{ServiceName: "AWS Lambda", MetaData: map[string]interface{}{"region": "us-east-1"},
+			Resources: []ResourceMetadata{{ResourceID: "resize", MetaData: map[string]interface{}{"runtime": "python3.12"}}}},
+	}
+	uncovered := []UncoveredSpend{
+		{ServiceName: "Amazon Elastic Compute Cloud - Compute", InstanceFamily: "m5", Region: "eu-west-1"},
+		{ServiceName: "Amazon Elastic Compute Cloud - Compute", InstanceFamily: "m5", Region: "us-east-1"},
+		{ServiceName: "Amazon Relational Database Service", InstanceFamily: instanceFamily("db.r6g.2xlarge"), Region: "eu-west-1"},
+		{ServiceName: "AWS Lambda", Region: "us-east-1"},
+	}
+
+	matchUncoveredResources(uncovered, services)
+	assert.Equal(t, []string{"i-1", "i-2"}, uncovered[0].ResourceIDs)
+	assert.Equal(t, []string{}, uncovered[1].ResourceIDs)
+	// Clusters are counted through their member instances, resources without an instance type match no family
+	assert.Equal(t, []string{"orders", "orders-1"}, uncovered[2].ResourceIDs)
+	assert.Equal(t, []string{"resize"}, uncovered[3].ResourceIDs)
+
+	assert.Equal(t, "m5", coverageAttribute(map[string]string{"instanceFamily": "m5"}, "INSTANCE_FAMILY"))
+}
@@ -0,0 +1,553 @@
+package entity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	cwTypes "github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	ecsTypes "github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/aws/aws-sdk-go-v2/service/eks"
+)
+
+// Task and container instance counts change while services and clusters scale and deploy
+func init() {
+	addVolatileMetadataKeys("running_count", "pending_count", "running_tasks", "pending_tasks", "registered_container_instances")
+}
+
+// CloudWatch namespaces that Container Insights publishes cluster metrics to
+var containerInsightsNamespaces = []string{
+	"ContainerInsights",
+	"ECS/ContainerInsights",
+	"ContainerInsights/Prometheus",
+}
+
+// listEKSClusters retrieves EKS clusters with their nodegroups and addons
+func listEKSClusters(cfg aws.Config) ([]ResourceMetadata, error) {
+	svc := eks.NewFromConfig(cfg)
+	cwSvc := cloudwatch.NewFromConfig(cfg)
+
+	var resources []ResourceMetadata
+	var describeErr error
+	failed := 0
+	paginator := eks.NewListClustersPaginator(svc, &eks.ListClustersInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return nil, err
+		}
+
+		for _, name := range page.Clusters {
+			desc, err := svc.DescribeCluster(context.TODO(), &eks.DescribeClusterInput{
+				Name: aws.String(name),
+			})
+			if err != nil {
+				log.Printf("Error describing EKS cluster: %s\n", name)
+				failed++
+				describeErr = err
+				continue
+			}
+			cluster := desc.Cluster
+
+			metadata := map[string]interface{}{
+				"resource_type":    "cluster",
+				"name":             name,
+				"version":          aws.ToString(cluster.Version),
+				"platform_version": aws.ToString(cluster.PlatformVersion),
+				"status":           cluster.Status,
+				"endpoint":         aws.ToString(cluster.Endpoint),
+				"role_arn":         aws.ToString(cluster.RoleArn),
+				"created_at":       cluster.CreatedAt,
+				"tags":             cluster.Tags,
+			}
+			if cluster.ResourcesVpcConfig != nil {
+				metadata["vpc_id"] = aws.ToString(cluster.ResourcesVpcConfig.VpcId)
+				metadata["subnets"] = cluster.ResourcesVpcConfig.SubnetIds
+				metadata["security_groups"] = cluster.ResourcesVpcConfig.SecurityGroupIds
+			}
+
+			// The cluster is kept with whatever could be described
+			nodegroups, nodegroupsErr := getEKSNodegroups(svc, name)
+			metadata["nodegroups"] = nodegroups
+			addons, addonsErr := getEKSAddons(svc, name)
+			metadata["addons"] = addons
+			profiles, profilesErr := getEKSFargateProfiles(svc, name)
+			metadata["fargate_profiles"] = profiles
+			if err := errors.Join(nodegroupsErr, addonsErr, profilesErr); err != nil {
+				log.Printf("Error describing EKS cluster %s: %v\n", name, err)
+				failed++
+				describeErr = err
+			}
+			metadata["container_insights_namespaces"] = getContainerInsightsNamespaces(cwSvc, name)
+			setCloudWatchRef(metadata, "ContainerInsights", map[string]string{"ClusterName": name})
+
+			resources = append(resources, ResourceMetadata{
+				ResourceID: aws.ToString(cluster.Arn),
+				MetaData:   metadata,
+			})
+		}
+	}
+
+	if describeErr != nil {
+		return resources, &partialResourcesError{Step: "eks_clusters",
+			Err: fmt.Errorf("describing %d EKS clusters: %w", failed, describeErr)}
+	}
+	return resources, nil
+}
+
+func getEKSNodegroups(svc *eks.Client, clusterName string) ([]map[string]interface{}, error) {
+	var names []string
+	paginator := eks.NewListNodegroupsPaginator(svc, &eks.ListNodegroupsInput{
+		ClusterName: aws.String(clusterName),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, page.Nodegroups...)
+	}
+
+	var nodegroups []map[string]interface{}
+	var describeErr error
+	for _, name := range names {
+		desc, err := svc.DescribeNodegroup(context.TODO(), &eks.DescribeNodegroupInput{
+			ClusterName:   aws.String(clusterName),
+			NodegroupName: aws.String(name),
+		})
+		if err != nil {
+			describeErr = err
+			continue
+		}
+		nodegroup := desc.Nodegroup
+
+		metadata := map[string]interface{}{
+			"name":            name,
+			"arn":             aws.ToString(nodegroup.NodegroupArn),
+			"status":          nodegroup.Status,
+			"instance_types":  nodegroup.InstanceTypes,
+			"capacity_type":   nodegroup.CapacityType,
+			"ami_type":        nodegroup.AmiType,
+			"version":         aws.ToString(nodegroup.Version),
+			"release_version": aws.ToString(nodegroup.ReleaseVersion),
+			"subnets":         nodegroup.Subnets,
+			"tags":            nodegroup.Tags,
+		}
+		if nodegroup.ScalingConfig != nil {
+			metadata["min_size"] = aws.ToInt32(nodegroup.ScalingConfig.MinSize)
+			metadata["max_size"] = aws.ToInt32(nodegroup.ScalingConfig.MaxSize)
+			metadata["desired_size"] = aws.ToInt32(nodegroup.ScalingConfig.DesiredSize)
+		}
+		if nodegroup.Resources != nil {
+			var groups []string
+			for _, group := range nodegroup.Resources.AutoScalingGroups {
+				groups = append(groups, aws.ToString(group.Name))
+			}
+			metadata["auto_scaling_groups"] = groups
+		}
+
+		nodegroups = append(nodegroups, metadata)
+	}
+	return nodegroups, describeErr
+}
+
+func getEKSAddons(svc *eks.Client, clusterName string) ([]map[string]interface{}, error) {
+	var names []string
+	paginator := eks.NewListAddonsPaginator(svc, &eks.ListAddonsInput{
+		ClusterName: aws.String(clusterName),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, page.Addons...)
+	}
+
+	var addons []map[string]interface{}
+	var describeErr error
+	for _, name := range names {
+		desc, err := svc.DescribeAddon(context.TODO(), &eks.DescribeAddonInput{
+			ClusterName: aws.String(clusterName),
+			AddonName:   aws.String(name),
+		})
+		if err != nil {
+			describeErr = err
+			continue
+		}
+
+		addons = append(addons, map[string]interface{}{
+			"name":                     name,
+			"version":                  aws.ToString(desc.Addon.AddonVersion),
+			"status":                   desc.Addon.Status,
+			"service_account_role_arn": aws.ToString(desc.Addon.ServiceAccountRoleArn),
+		})
+	}
+	return addons, describeErr
+}
+
+func getEKSFargateProfiles(svc *eks.Client, clusterName string) ([]map[string]interface{}, error) {
+	var names []string
+	paginator := eks.NewListFargateProfilesPaginator(svc, &eks.ListFargateProfilesInput{
+		ClusterName: aws.String(clusterName),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, page.FargateProfileNames...)
+	}
+
+	var profiles []map[string]interface{}
+	var describeErr error
+	for _, name := range names {
+		desc, err := svc.DescribeFargateProfile(context.TODO(), &eks.DescribeFargateProfileInput{
+			ClusterName:        aws.String(clusterName),
+			FargateProfileName: aws.String(name),
+		})
+		if err != nil {
+			describeErr = err
+			continue
+		}
+
+		var namespaces []string
+		for _, selector := range desc.FargateProfile.Selectors {
+			namespaces = append(namespaces, aws.ToString(selector.Namespace))
+		}
+
+		profiles = append(profiles, map[string]interface{}{
+			"name":       name,
+			"status":     desc.FargateProfile.Status,
+			"namespaces": namespaces,
+			"subnets":    desc.FargateProfile.Subnets,
+		})
+	}
+	return profiles, describeErr
+}
+
+// listECSClusters retrieves ECS clusters and their services as separate resources
+func listECSClusters(cfg aws.Config) ([]ResourceMetadata, error) {
+	svc := ecs.NewFromConfig(cfg)
+	cwSvc := cloudwatch.NewFromConfig(cfg)
+
+	var clusterArns []string
+	paginator := ecs.NewListClustersPaginator(svc, &ecs.ListClustersInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return nil, err
+		}
+		clusterArns = append(clusterArns, page.ClusterArns...)
+	}
+
+	var resources []ResourceMetadata
+	// DescribeClusters accepts up to 100 clusters per call
+	for _, batch := range chunkStrings(clusterArns, 100) {
+		result, err := svc.DescribeClusters(context.TODO(), &ecs.DescribeClustersInput{
+			Clusters: batch,
+			Include:  []ecsTypes.ClusterField{ecsTypes.ClusterFieldSettings, ecsTypes.ClusterFieldTags},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, cluster := range result.Clusters {
+			name := aws.ToString(cluster.ClusterName)
+
+			settings := make(map[string]string)
+			for _, setting := range cluster.Settings {
+				settings[string(setting.Name)] = aws.ToString(setting.Value)
+			}
+
+			metadata := map[string]interface{}{
+				"resource_type":                      "cluster",
+				"name":                               name,
+				"status":                             aws.ToString(cluster.Status),
+				"running_tasks":                      cluster.RunningTasksCount,
+				"pending_tasks":                      cluster.PendingTasksCount,
+				"active_services":                    cluster.ActiveServicesCount,
+				"registered_container_instances":     cluster.RegisteredContainerInstancesCount,
+				"settings":                           settings,
+				"default_capacity_provider_strategy": getECSCapacityProviderStrategy(cluster.DefaultCapacityProviderStrategy),
+				"capacity_providers":                 getECSCapacityProviders(svc, cluster.CapacityProviders),
+				"container_insights_namespaces":      getContainerInsightsNamespaces(cwSvc, name),
+				"tags":                               getECSTags(cluster.Tags),
+			}
+			setCloudWatchRef(metadata, "AWS/ECS", map[string]string{"ClusterName": name})
+
+			resources = append(resources, ResourceMetadata{
+				ResourceID: aws.ToString(cluster.ClusterArn),
+				MetaData:   metadata,
+			})
+
+			resources = append(resources, listECSServices(svc, cluster)...)
+		}
+	}
+
+	return resources, nil
+}
+
+// ecsListing keeps the ECS clusters listed during one collection, ECS and Fargate are both collected from them
+type ecsListing struct {
+	listed    bool
+	resources []ResourceMetadata
+	err       error
+}
+
+func (l *ecsListing) list(cfg aws.Config) ([]ResourceMetadata, error) {
+	if !l.listed {
+		l.resources, l.err = listECSClusters(cfg)
+		l.listed = true
+	}
+	return l.resources, l.err
+}
+
+// fargateWorkloads returns the ECS services that run on Fargate
+func fargateWorkloads(resources []ResourceMetadata) []ResourceMetadata {
+	var fargate []ResourceMetadata
+	for _, resource := range resources {
+		if fargateService, ok := resource.MetaData["fargate"].(bool); ok && fargateService {
+			fargate = append(fargate, resource)
+		}
+	}
+	return fargate
+}
+
+// referenceFargateWorkloads keeps the Fargate workloads with ECS when both services are collected. A resource
+// listed under both would get its alarms and metric links on one copy only and be exported twice, so Fargate
+// keeps the ARNs of its workloads in ecs_services instead.
+func referenceFargateWorkloads(services []ServiceMetadata) {
+	ecsCollected := false
+	for _, service := range services {
+		if code, _ := mapServiceNameToCode(service.ServiceName); code == "ecs" {
+			ecsCollected = true
+		}
+	}
+	if !ecsCollected {
+		return
+	}
+	for i, service := range services {
+		if code, _ := mapServiceNameToCode(service.ServiceName); code != "fargate" {
+			continue
+		}
+		arns := []string{}
+		for _, resource := range service.Resources {
+			arns = append(arns, resource.ResourceID)
+		}
+		services[i].MetaData["ecs_services"] = arns
+		services[i].Resources = []ResourceMetadata{}
+	}
+}
+
+func listECSServices(svc *ecs.Client, cluster ecsTypes.Cluster) []ResourceMetadata {
+	var serviceArns []string
+	paginator := ecs.NewListServicesPaginator(svc, &ecs.ListServicesInput{
+		Cluster: cluster.ClusterArn,
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			log.Printf("Error listing ECS services for cluster: %s\n", aws.ToString(cluster.ClusterName))
+			break
+		}
+		serviceArns = append(serviceArns, page.ServiceArns...)
+	}
+
+	var resources []ResourceMetadata
+	// DescribeServices accepts up to 10 services per call
+	for _, batch := range chunkStrings(serviceArns, 10) {
+		result, err := svc.DescribeServices(context.TODO(), &ecs.DescribeServicesInput{
+			Cluster:  cluster.ClusterArn,
+			Services: batch,
+			Include:  []ecsTypes.ServiceField{ecsTypes.ServiceFieldTags},
+		})
+		if err != nil {
+			log.Printf("Error describing ECS services for cluster: %s\n", aws.ToString(cluster.ClusterName))
+			continue
+		}
+
+		for _, service := range result.Services {
+			name := aws.ToString(service.ServiceName)
+
+			var targetGroups []string
+			for _, lb := range service.LoadBalancers {
+				if lb.TargetGroupArn != nil {
+					targetGroups = append(targetGroups, *lb.TargetGroupArn)
+				}
+			}
+
+			strategy := getECSCapacityProviderStrategy(service.CapacityProviderStrategy)
+			metadata := map[string]interface{}{
+				"resource_type":              "service",
+				"name":                       name,
+				"cluster":                    aws.ToString(cluster.ClusterName),
+				"status":                     aws.ToString(service.Status),
+				"launch_type":                service.LaunchType,
+				"fargate":                    isFargateService(service),
+				"platform_version":           aws.ToString(service.PlatformVersion),
+				"desired_count":              service.DesiredCount,
+				"running_count":              service.RunningCount,
+				"pending_count":              service.PendingCount,
+				"capacity_provider_strategy": strategy,
+				"target_groups":              targetGroups,
+				"task_definition":            getECSTaskDefinition(svc, aws.ToString(service.TaskDefinition)),
+				"created_at":                 service.CreatedAt,
+				"tags":                       getECSTags(service.Tags),
+			}
+			setCloudWatchRef(metadata, "AWS/ECS", map[string]string{
+				"ClusterName": aws.ToString(cluster.ClusterName),
+				"ServiceName": name,
+			})
+
+			resources = append(resources, ResourceMetadata{
+				ResourceID: aws.ToString(service.ServiceArn),
+				MetaData:   metadata,
+			})
+		}
+	}
+
+	return resources
+}
+
+// isFargateService reports whether a service runs on Fargate, by launch type or by a Fargate capacity provider
+func isFargateService(service ecsTypes.Service) bool {
+	if service.LaunchType == ecsTypes.LaunchTypeFargate {
+		return true
+	}
+	for _, item := range service.CapacityProviderStrategy {
+		provider := aws.ToString(item.CapacityProvider)
+		if provider == "FARGATE" || provider == "FARGATE_SPOT" {
+			return true
+		}
+	}
+	return false
+}
+
+func getECSTaskDefinition(svc *ecs.Client, taskDefinitionArn string) map[string]interface{} {
+	if taskDefinitionArn == "" {
+		return nil
+	}
+
+	result, err := svc.DescribeTaskDefinition(context.TODO(), &ecs.DescribeTaskDefinitionInput{
+		TaskDefinition: aws.String(taskDefinitionArn),
+	})
+	if err != nil {
+		return nil
+	}
+	taskDefinition := result.TaskDefinition
+
+	var containers []map[string]interface{}
+	for _, container := range taskDefinition.ContainerDefinitions {
+		metadata := map[string]interface{}{
+			"name":   aws.ToString(container.Name),
+			"image":  aws.ToString(container.Image),
+			"cpu":    container.Cpu,
+			"memory": container.Memory,
+		}
+		if logging := container.LogConfiguration; logging != nil && logging.LogDriver == ecsTypes.LogDriverAwslogs {
+			metadata["log_group"] = logging.Options["awslogs-group"]
+		}
+		containers = append(containers, metadata)
+	}
+
+	return map[string]interface{}{
+		"arn":                      taskDefinitionArn,
+		"family":                   aws.ToString(taskDefinition.Family),
+		"revision":                 taskDefinition.Revision,
+		"cpu":                      aws.ToString(taskDefinition.Cpu),
+		"memory":                   aws.ToString(taskDefinition.Memory),
+		"network_mode":             taskDefinition.NetworkMode,
+		"requires_compatibilities": taskDefinition.RequiresCompatibilities,
+		"containers":               containers,
+	}
+}
+
+func getECSCapacityProviders(svc *ecs.Client, names []string) []map[string]interface{} {
+	if len(names) == 0 {
+		return nil
+	}
+
+	result, err := svc.DescribeCapacityProviders(context.TODO(), &ecs.DescribeCapacityProvidersInput{
+		CapacityProviders: names,
+	})
+	if err != nil {
+		return nil
+	}
+
+	var providers []map[string]interface{}
+	for _, provider := range result.CapacityProviders {
+		metadata := map[string]interface{}{
+			"name":   aws.ToString(provider.Name),
+			"status": provider.Status,
+		}
+		if asg := provider.AutoScalingGroupProvider; asg != nil {
+			metadata["auto_scaling_group_arn"] = aws.ToString(asg.AutoScalingGroupArn)
+			if asg.ManagedScaling != nil {
+				metadata["managed_scaling"] = asg.ManagedScaling.Status
+				metadata["target_capacity"] = aws.ToInt32(asg.ManagedScaling.TargetCapacity)
+			}
+		}
+		providers = append(providers, metadata)
+	}
+	return providers
+}
+
+func getECSCapacityProviderStrategy(items []ecsTypes.CapacityProviderStrategyItem) []map[string]interface{} {
+	var strategy []map[string]interface{}
+	for _, item := range items {
+		strategy = append(strategy, map[string]interface{}{
+			"capacity_provider": aws.ToString(item.CapacityProvider),
+			"base":              item.Base,
+			"weight":            item.Weight,
+		})
+	}
+	return strategy
+}
+
+func getECSTags(tags []ecsTypes.Tag) map[string]string {
+	tagMap := make(map[string]string)
+	for _, tag := range tags {
+		if tag.Key != nil && tag.Value != nil {
+			tagMap[*tag.Key] = *tag.Value
+		}
+	}
+	return tagMap
+}
+
+// getContainerInsightsNamespaces returns the Container Insights namespaces that have metrics for a cluster
+func getContainerInsightsNamespaces(svc *cloudwatch.Client, clusterName string) []string {
+	var namespaces []string
+	for _, namespace := range containerInsightsNamespaces {
+		result, err := svc.ListMetrics(context.TODO(), &cloudwatch.ListMetricsInput{
+			Namespace: aws.String(namespace),
+			Dimensions: []cwTypes.DimensionFilter{{
+				Name:  aws.String("ClusterName"),
+				Value: aws.String(clusterName),
+			}},
+		})
+		if err != nil {
+			continue
+		}
+		if len(result.Metrics) > 0 {
+			namespaces = append(namespaces, namespace)
+		}
+	}
+	return namespaces
+}
+
+func chunkStrings(values []string, size int) [][]string {
+	var chunks [][]string
+	for len(values) > size {
+		chunks = append(chunks, values[:size])
+		values = values[size:]
+	}
+	if len(values) > 0 {
+		chunks = append(chunks, values)
+	}
+	return chunks
+}
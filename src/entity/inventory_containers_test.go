@@ -0,0 +1,81 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ecsTypes "github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChunkStrings(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []string
+		size   int
+		want   [][]string
+	}{
+		{"empty", nil, 10, nil},
+		{"one partial chunk", []string{"a", "b"}, 10, [][]string{{"a", "b"}}},
+		{"exact chunks", []string{"a", "b", "c", "d"}, 2, [][]string{{"a", "b"}, {"c", "d"}}},
+		{"last chunk partial", []string{"a", "b", "c"}, 2, [][]string{{"a", "b"}, {"c"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, chunkStrings(tt.values, tt.size))
+		})
+	}
+}
+
+func TestIsFargateService(t *testing.T) {
+	strategy := func(providers ...string) []ecsTypes.CapacityProviderStrategyItem {
+		var items []ecsTypes.CapacityProviderStrategyItem
+		for _, provider := range providers {
+			items = append(items, ecsTypes.CapacityProviderStrategyItem{CapacityProvider: aws.String(provider)})
+		}
+		return items
+	}
+	tests := []struct {
+		name    string
+		service ecsTypes.Service
+		want    bool
+	}{
+		{"fargate launch type", ecsTypes.Service{LaunchType: ecsTypes.LaunchTypeFargate}, true},
+		{"ec2 launch type", ecsTypes.Service{LaunchType: ecsTypes.LaunchTypeEc2}, false},
+		{"fargate spot provider", ecsTypes.Service{CapacityProviderStrategy: strategy("FARGATE_SPOT")}, true},
+		{"mixed providers", ecsTypes.Service{CapacityProviderStrategy: strategy("asg-provider", "FARGATE")}, true},
+		{"auto scaling group provider", ecsTypes.Service{CapacityProviderStrategy: strategy("asg-provider")}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, isFargateService(tt.service))
+		})
+	}
+}
+
+func TestFargateWorkloads(t *testing.T) {
+	resources := []ResourceMetadata{
+		{ResourceID: "arn:aws:ecs:eu-west-1:123:cluster/web", MetaData: map[string]interface{}{"resource_type": "cluster"}},
+		{ResourceID: "arn:aws:ecs:eu-west-1:123:service/web/api", MetaData: map[string]interface{}{"resource_type": "service", "fargate": true}},
+		{ResourceID: "arn:aws:ecs:eu-west-1:123:service/web/worker", MetaData: map[string]interface{}{"resource_type": "service", "fargate": false}},
+	}
+
+	fargate := fargateWorkloads(resources)
+
+	assert.Len(t, fargate, 1)
+	assert.Equal(t, "arn:aws:ecs:eu-west-1:123:service/web/api", fargate[0].ResourceID)
+
+	// Without ECS the workloads are listed under Fargate
+	services := []ServiceMetadata{
+		{ServiceName: "AWS Fargate - Serverless Containers", Resources: fargate, MetaData: map[string]interface{}{}},
+	}
+	referenceFargateWorkloads(services)
+	assert.Len(t, services[0].Resources, 1)
+
+	// With ECS they are only referenced from Fargate
+	services = append(services, ServiceMetadata{ServiceName: "Amazon Elastic Container Service", Resources: resources, MetaData: map[string]interface{}{}})
+	referenceFargateWorkloads(services)
+	assert.Empty(t, services[0].Resources)
+	assert.Equal(t, []string{"arn:aws:ecs:eu-west-1:123:service/web/api"}, services[0].MetaData["ecs_services"])
+	assert.Len(t, services[1].Resources, 3)
+}
@@ -0,0 +1,364 @@
+package entity
+
+import (
+	"context"
+	"log"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/efs"
+	efsTypes "github.com/aws/aws-sdk-go-v2/service/efs/types"
+	"github.com/aws/aws-sdk-go-v2/service/emr"
+	emrTypes "github.com/aws/aws-sdk-go-v2/service/emr/types"
+	"github.com/aws/aws-sdk-go-v2/service/glue"
+	"github.com/aws/aws-sdk-go-v2/service/rds"
+	rdsTypes "github.com/aws/aws-sdk-go-v2/service/rds/types"
+	"github.com/aws/aws-sdk-go-v2/service/redshift"
+	redshiftTypes "github.com/aws/aws-sdk-go-v2/service/redshift/types"
+)
+
+// listRDSClusters retrieves Aurora and Multi-AZ DB clusters with their member instances
+func listRDSClusters(cfg aws.Config) ([]ResourceMetadata, error) {
+	svc := rds.NewFromConfig(cfg)
+
+	var resources []ResourceMetadata
+	paginator := rds.NewDescribeDBClustersPaginator(svc, &rds.DescribeDBClustersInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return nil, err
+		}
+
+		for _, cluster := range page.DBClusters {
+			if !isRDSEngine(aws.ToString(cluster.Engine)) {
+				continue
+			}
+			// Clusters are listed with the RDS instances, the ARN keeps a cluster and an instance of the same
+			// name apart
+			resources = append(resources, ResourceMetadata{
+				ResourceID: aws.ToString(cluster.DBClusterArn),
+				MetaData:   rdsClusterMetadata(cluster),
+			})
+		}
+	}
+
+	return resources, nil
+}
+
+// referenceAuroraClusters keeps the Aurora clusters with RDS when both services are collected, the same way
+// referenceFargateWorkloads keeps Fargate workloads with ECS. Aurora keeps the ARNs of its clusters in
+// rds_clusters instead.
+func referenceAuroraClusters(services []ServiceMetadata) {
+	rdsCollected := false
+	for _, service := range services {
+		if code, _ := mapServiceNameToCode(service.ServiceName); code == "rds" {
+			rdsCollected = true
+		}
+	}
+	if !rdsCollected {
+		return
+	}
+	for i, service := range services {
+		if code, _ := mapServiceNameToCode(service.ServiceName); code != "aurora" {
+			continue
+		}
+		arns := []string{}
+		for _, resource := range service.Resources {
+			arns = append(arns, resource.ResourceID)
+		}
+		services[i].MetaData["rds_clusters"] = arns
+		services[i].Resources = []ResourceMetadata{}
+	}
+}
+
+// Neptune and DocumentDB clusters and instances are described by the RDS API as well
+var nonRDSEngines = map[string]bool{
+	"neptune": true,
+	"docdb":   true,
+}
+
+// isRDSEngine reports whether a cluster or instance engine is billed as RDS or Aurora
+func isRDSEngine(engine string) bool {
+	return !nonRDSEngines[engine]
+}
+
+// rdsClusterMetadata maps a cluster to its metadata
+func rdsClusterMetadata(cluster rdsTypes.DBCluster) map[string]interface{} {
+	id := aws.ToString(cluster.DBClusterIdentifier)
+
+	var members []map[string]interface{}
+	for _, member := range cluster.DBClusterMembers {
+		members = append(members, map[string]interface{}{
+			"db_instance_identifier": aws.ToString(member.DBInstanceIdentifier),
+			"writer":                 aws.ToBool(member.IsClusterWriter),
+			"promotion_tier":         aws.ToInt32(member.PromotionTier),
+		})
+	}
+
+	metadata := map[string]interface{}{
+		"resource_type":     "cluster",
+		"name":              id,
+		"engine":            aws.ToString(cluster.Engine),
+		"engine_version":    aws.ToString(cluster.EngineVersion),
+		"engine_mode":       aws.ToString(cluster.EngineMode),
+		"status":            aws.ToString(cluster.Status),
+		"endpoint":          aws.ToString(cluster.Endpoint),
+		"reader_endpoint":   aws.ToString(cluster.ReaderEndpoint),
+		"multi_az":          aws.ToBool(cluster.MultiAZ),
+		"storage_encrypted": aws.ToBool(cluster.StorageEncrypted),
+		"members":           members,
+		"tags":              getRDSTags(cluster.TagList),
+	}
+	if cluster.DBClusterInstanceClass != nil {
+		metadata["instance_type"] = *cluster.DBClusterInstanceClass
+	}
+	if scaling := cluster.ServerlessV2ScalingConfiguration; scaling != nil {
+		metadata["serverless_v2_min_capacity"] = aws.ToFloat64(scaling.MinCapacity)
+		metadata["serverless_v2_max_capacity"] = aws.ToFloat64(scaling.MaxCapacity)
+	}
+	setCloudWatchRef(metadata, "AWS/RDS", map[string]string{"DBClusterIdentifier": id})
+	return metadata
+}
+
+func getRDSTags(tags []rdsTypes.Tag) map[string]string {
+	tagMap := make(map[string]string)
+	for _, tag := range tags {
+		if tag.Key != nil && tag.Value != nil {
+			tagMap[*tag.Key] = *tag.Value
+		}
+	}
+	return tagMap
+}
+
+// listRedshiftClusters retrieves provisioned Redshift clusters
+func listRedshiftClusters(cfg aws.Config) ([]ResourceMetadata, error) {
+	svc := redshift.NewFromConfig(cfg)
+
+	var resources []ResourceMetadata
+	paginator := redshift.NewDescribeClustersPaginator(svc, &redshift.DescribeClustersInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return nil, err
+		}
+
+		for _, cluster := range page.Clusters {
+			id := aws.ToString(cluster.ClusterIdentifier)
+			metadata := map[string]interface{}{
+				"node_type":       aws.ToString(cluster.NodeType),
+				"number_of_nodes": aws.ToInt32(cluster.NumberOfNodes),
+				"status":          aws.ToString(cluster.ClusterStatus),
+				"version":         aws.ToString(cluster.ClusterVersion),
+				"db_name":         aws.ToString(cluster.DBName),
+				"vpc_id":          aws.ToString(cluster.VpcId),
+				"encrypted":       aws.ToBool(cluster.Encrypted),
+				"tags":            getRedshiftTags(cluster.Tags),
+			}
+			if cluster.Endpoint != nil {
+				metadata["endpoint"] = aws.ToString(cluster.Endpoint.Address)
+				metadata["port"] = aws.ToInt32(cluster.Endpoint.Port)
+			}
+			setCloudWatchRef(metadata, "AWS/Redshift", map[string]string{"ClusterIdentifier": id})
+
+			resources = append(resources, ResourceMetadata{
+				ResourceID: id,
+				MetaData:   metadata,
+			})
+		}
+	}
+
+	return resources, nil
+}
+
+func getRedshiftTags(tags []redshiftTypes.Tag) map[string]string {
+	tagMap := make(map[string]string)
+	for _, tag := range tags {
+		if tag.Key != nil && tag.Value != nil {
+			tagMap[*tag.Key] = *tag.Value
+		}
+	}
+	return tagMap
+}
+
+// listEMRClusters retrieves EMR clusters that have not been terminated
+func listEMRClusters(cfg aws.Config) ([]ResourceMetadata, error) {
+	svc := emr.NewFromConfig(cfg)
+
+	var resources []ResourceMetadata
+	paginator := emr.NewListClustersPaginator(svc, &emr.ListClustersInput{
+		ClusterStates: []emrTypes.ClusterState{
+			emrTypes.ClusterStateStarting,
+			emrTypes.ClusterStateBootstrapping,
+			emrTypes.ClusterStateRunning,
+			emrTypes.ClusterStateWaiting,
+		},
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return nil, err
+		}
+
+		for _, summary := range page.Clusters {
+			id := aws.ToString(summary.Id)
+			metadata := map[string]interface{}{
+				"name":                      aws.ToString(summary.Name),
+				"normalized_instance_hours": aws.ToInt32(summary.NormalizedInstanceHours),
+			}
+			if summary.Status != nil {
+				metadata["state"] = summary.Status.State
+			}
+
+			desc, err := svc.DescribeCluster(context.TODO(), &emr.DescribeClusterInput{
+				ClusterId: summary.Id,
+			})
+			if err != nil {
+				log.Printf("Error describing EMR cluster: %s\n", id)
+			} else {
+				cluster := desc.Cluster
+
+				var applications []string
+				for _, application := range cluster.Applications {
+					applications = append(applications, aws.ToString(application.Name))
+				}
+
+				tags := make(map[string]string)
+				for _, tag := range cluster.Tags {
+					if tag.Key != nil && tag.Value != nil {
+						tags[*tag.Key] = *tag.Value
+					}
+				}
+
+				metadata["release_label"] = aws.ToString(cluster.ReleaseLabel)
+				metadata["applications"] = applications
+				metadata["instance_collection_type"] = cluster.InstanceCollectionType
+				metadata["auto_terminate"] = aws.ToBool(cluster.AutoTerminate)
+				metadata["tags"] = tags
+			}
+			setCloudWatchRef(metadata, "AWS/ElasticMapReduce", map[string]string{"JobFlowId": id})
+
+			resources = append(resources, ResourceMetadata{
+				ResourceID: id,
+				MetaData:   metadata,
+			})
+		}
+	}
+
+	return resources, nil
+}
+
+// listGlueResources retrieves Glue jobs and crawlers as separate resources
+func listGlueResources(cfg aws.Config) ([]ResourceMetadata, error) {
+	svc := glue.NewFromConfig(cfg)
+
+	var resources []ResourceMetadata
+	jobs := glue.NewGetJobsPaginator(svc, &glue.GetJobsInput{})
+	for jobs.HasMorePages() {
+		page, err := jobs.NextPage(context.TODO())
+		if err != nil {
+			return nil, err
+		}
+
+		for _, job := range page.Jobs {
+			name := aws.ToString(job.Name)
+			metadata := map[string]interface{}{
+				"resource_type":     "job",
+				"glue_version":      aws.ToString(job.GlueVersion),
+				"worker_type":       job.WorkerType,
+				"number_of_workers": aws.ToInt32(job.NumberOfWorkers),
+				"max_capacity":      aws.ToFloat64(job.MaxCapacity),
+				"timeout_minutes":   aws.ToInt32(job.Timeout),
+				"max_retries":       job.MaxRetries,
+				"last_modified":     job.LastModifiedOn,
+			}
+			if job.Command != nil {
+				metadata["command"] = aws.ToString(job.Command.Name)
+			}
+			// Job metrics aggregated over all runs are published with JobRunId=ALL
+			setCloudWatchRef(metadata, "Glue", map[string]string{"JobName": name, "JobRunId": "ALL"})
+
+			resources = append(resources, ResourceMetadata{
+				ResourceID: name,
+				MetaData:   metadata,
+			})
+		}
+	}
+
+	crawlers := glue.NewGetCrawlersPaginator(svc, &glue.GetCrawlersInput{})
+	for crawlers.HasMorePages() {
+		page, err := crawlers.NextPage(context.TODO())
+		if err != nil {
+			return nil, err
+		}
+
+		for _, crawler := range page.Crawlers {
+			metadata := map[string]interface{}{
+				"resource_type": "crawler",
+				"state":         crawler.State,
+				"database_name": aws.ToString(crawler.DatabaseName),
+			}
+			if crawler.Schedule != nil {
+				metadata["schedule"] = aws.ToString(crawler.Schedule.ScheduleExpression)
+			}
+			if crawler.LastCrawl != nil {
+				metadata["last_crawl_status"] = crawler.LastCrawl.Status
+				metadata["last_crawl_time"] = crawler.LastCrawl.StartTime
+			}
+
+			resources = append(resources, ResourceMetadata{
+				ResourceID: aws.ToString(crawler.Name),
+				MetaData:   metadata,
+			})
+		}
+	}
+
+	return resources, nil
+}
+
+// listEFSFileSystems retrieves EFS file systems
+func listEFSFileSystems(cfg aws.Config) ([]ResourceMetadata, error) {
+	svc := efs.NewFromConfig(cfg)
+
+	var resources []ResourceMetadata
+	paginator := efs.NewDescribeFileSystemsPaginator(svc, &efs.DescribeFileSystemsInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return nil, err
+		}
+
+		for _, fileSystem := range page.FileSystems {
+			id := aws.ToString(fileSystem.FileSystemId)
+			metadata := map[string]interface{}{
+				"name":                    aws.ToString(fileSystem.Name),
+				"life_cycle_state":        fileSystem.LifeCycleState,
+				"performance_mode":        fileSystem.PerformanceMode,
+				"throughput_mode":         fileSystem.ThroughputMode,
+				"number_of_mount_targets": fileSystem.NumberOfMountTargets,
+				"encrypted":               aws.ToBool(fileSystem.Encrypted),
+				"creation_time":           fileSystem.CreationTime,
+				"tags":                    getEFSTags(fileSystem.Tags),
+			}
+			if fileSystem.SizeInBytes != nil {
+				metadata["size_bytes"] = fileSystem.SizeInBytes.Value
+			}
+			setCloudWatchRef(metadata, "AWS/EFS", map[string]string{"FileSystemId": id})
+
+			resources = append(resources, ResourceMetadata{
+				ResourceID: id,
+				MetaData:   metadata,
+			})
+		}
+	}
+
+	return resources, nil
+}
+
+func getEFSTags(tags []efsTypes.Tag) map[string]string {
+	tagMap := make(map[string]string)
+	for _, tag := range tags {
+		if tag.Key != nil && tag.Value != nil {
+			tagMap[*tag.Key] = *tag.Value
+		}
+	}
+	return tagMap
+}
@@ -0,0 +1,82 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	rdsTypes "github.com/aws/aws-sdk-go-v2/service/rds/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsRDSEngine(t *testing.T) {
+	for _, engine := range []string{"aurora-mysql", "aurora-postgresql", "mysql", "postgres"} {
+		assert.True(t, isRDSEngine(engine), engine)
+	}
+	for _, engine := range []string{"neptune", "docdb"} {
+		assert.False(t, isRDSEngine(engine), engine)
+	}
+}
+
+func TestRDSClusterMetadata(t *testing.T) {
+	metadata := rdsClusterMetadata(rdsTypes.DBCluster{
+		DBClusterIdentifier: aws.String("orders"),
+		Engine:              aws.String("aurora-postgresql"),
+		EngineVersion:       aws.String("15.4"),
+		EngineMode:          aws.String("provisioned"),
+		Status:              aws.String("available"),
+		Endpoint:            aws.String("orders.cluster-abc.eu-west-1.rds.amazonaws.com"),
+		MultiAZ:             aws.Bool(true),
+		DBClusterMembers: []rdsTypes.DBClusterMember{
+			{DBInstanceIdentifier: aws.String("orders-1"), IsClusterWriter: aws.Bool(true), PromotionTier: aws.Int32(1)},
+			{DBInstanceIdentifier: aws.String("orders-2"), IsClusterWriter: aws.Bool(false), PromotionTier: aws.Int32(2)},
+		},
+		TagList: []rdsTypes.Tag{{Key: aws.String("team"), Value: aws.String("payments")}},
+		ServerlessV2ScalingConfiguration: &rdsTypes.ServerlessV2ScalingConfigurationInfo{
+			MinCapacity: aws.Float64(0.5),
+			MaxCapacity: aws.Float64(8),
+		},
+	})
+	assert.Equal(t, "cluster", metadata["resource_type"])
+	assert.Equal(t, "orders", metadata["name"])
+	assert.Equal(t, "aurora-postgresql", metadata["engine"])
+	assert.Equal(t, true, metadata["multi_az"])
+	assert.Equal(t, map[string]string{"team": "payments"}, metadata["tags"])
+	assert.Equal(t, 0.5, metadata["serverless_v2_min_capacity"])
+	assert.Equal(t, 8.0, metadata["serverless_v2_max_capacity"])
+	assert.NotContains(t, metadata, "instance_type")
+	assert.Equal(t, "AWS/RDS", metadata["cloudwatch_namespace"])
+
+	members := metadata["members"].([]map[string]interface{})
+	assert.Len(t, members, 2)
+	assert.Equal(t, "orders-1", members[0]["db_instance_identifier"])
+	assert.Equal(t, true, members[0]["writer"])
+	assert.Equal(t, int32(2), members[1]["promotion_tier"])
+
+	multiAZ := rdsClusterMetadata(rdsTypes.DBCluster{
+		DBClusterIdentifier:    aws.String("ledger"),
+		Engine:                 aws.String("postgres"),
+		DBClusterInstanceClass: aws.String("db.m6gd.large"),
+	})
+	assert.Equal(t, "db.m6gd.large", multiAZ["instance_type"])
+	assert.NotContains(t, multiAZ, "serverless_v2_min_capacity")
+}
+
+func TestReferenceAuroraClusters(t *testing.T) {
+	cluster := ResourceMetadata{ResourceID: "arn:aws:rds:eu-west-1:123:cluster:orders",
+		MetaData: map[string]interface{}{"resource_type": "cluster", "name": "orders"}}
+
+	// Without RDS the clusters are listed under Aurora
+	services := []ServiceMetadata{
+		{ServiceName: "Amazon Aurora", Resources: []ResourceMetadata{cluster}, MetaData: map[string]interface{}{}},
+	}
+	referenceAuroraClusters(services)
+	assert.Len(t, services[0].Resources, 1)
+
+	// With RDS they are only referenced from Aurora
+	services = append(services, ServiceMetadata{ServiceName: "Amazon Relational Database Service", MetaData: map[string]interface{}{},
+		Resources: []ResourceMetadata{cluster, {ResourceID: "orders", MetaData: map[string]interface{}{"resource_type": "instance"}}}})
+	referenceAuroraClusters(services)
+	assert.Empty(t, services[0].Resources)
+	assert.Equal(t, []string{"arn:aws:rds:eu-west-1:123:cluster:orders"}, services[0].MetaData["rds_clusters"])
+	assert.Len(t, services[1].Resources, 2)
+}
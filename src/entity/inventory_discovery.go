@@ -0,0 +1,158 @@
+package entity
+
+import (
+	"context"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudtrail"
+	ctTypes "github.com/aws/aws-sdk-go-v2/service/cloudtrail/types"
+)
+
+// Recent changes are read from a moving CloudTrail window
+func init() {
+	addVolatileMetadataKeys("recent_change", "recent_changes")
+}
+
+// LookupEvents allows two requests per second, so discovery stops after this many events
+const maxCloudTrailEvents = 5000
+
+// cloudTrailDiscovery holds the services and resources changed in a CloudTrail window
+type cloudTrailDiscovery struct {
+	// Event source of each service code, in order of activity
+	EventSources []string
+	// Write events of each service code, newest first
+	Changes map[string][]map[string]interface{}
+}
+
+// discoverServicesFromCloudTrail looks up write events in the window and groups them by service code.
+// Event sources the inventory has no collector for are ignored.
+func discoverServicesFromCloudTrail(cfg aws.Config, window time.Duration) (cloudTrailDiscovery, error) {
+	svc := cloudtrail.NewFromConfig(cfg)
+
+	endTime := time.Now()
+	var events []ctTypes.Event
+	paginator := cloudtrail.NewLookupEventsPaginator(svc, &cloudtrail.LookupEventsInput{
+		StartTime: aws.Time(endTime.Add(-window)),
+		EndTime:   aws.Time(endTime),
+		LookupAttributes: []ctTypes.LookupAttribute{
+			{
+				AttributeKey:   ctTypes.LookupAttributeKeyReadOnly,
+				AttributeValue: aws.String("false"),
+			},
+		},
+	})
+	for paginator.HasMorePages() && len(events) < maxCloudTrailEvents {
+		page, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return cloudTrailDiscovery{}, err
+		}
+		events = append(events, page.Events...)
+	}
+	if len(events) >= maxCloudTrailEvents {
+		log.Printf("Stopped CloudTrail discovery after %d events\n", len(events))
+	}
+
+	return groupCloudTrailEvents(events), nil
+}
+
+func groupCloudTrailEvents(events []ctTypes.Event) cloudTrailDiscovery {
+	discovery := cloudTrailDiscovery{Changes: make(map[string][]map[string]interface{})}
+	eventSources := make(map[string]string)
+	for _, event := range events {
+		eventSource := aws.ToString(event.EventSource)
+		code, ok := mapServiceNameToCode(eventSource)
+		if !ok {
+			continue
+		}
+		if _, ok := eventSources[code]; !ok {
+			eventSources[code] = eventSource
+		}
+
+		var resources []string
+		for _, resource := range event.Resources {
+			resources = append(resources, aws.ToString(resource.ResourceName))
+		}
+		discovery.Changes[code] = append(discovery.Changes[code], map[string]interface{}{
+			"event_name": aws.ToString(event.EventName),
+			"event_time": aws.ToTime(event.EventTime),
+			"username":   aws.ToString(event.Username),
+			"resources":  resources,
+		})
+	}
+
+	// Most active services first
+	codes := sortedKeys(eventSources)
+	sort.SliceStable(codes, func(i, j int) bool {
+		return len(discovery.Changes[codes[i]]) > len(discovery.Changes[codes[j]])
+	})
+	for _, code := range codes {
+		discovery.EventSources = append(discovery.EventSources, eventSources[code])
+	}
+	return discovery
+}
+
+// mergeDiscoveredServices appends the services of the event sources that are not already in the list, named as in
+// Cost Explorer so a service keeps its name once it shows up in billing
+func mergeDiscoveredServices(services []string, discovery cloudTrailDiscovery) []string {
+	covered := make(map[string]bool)
+	for _, service := range services {
+		if code, ok := mapServiceNameToCode(service); ok {
+			covered[code] = true
+		}
+	}
+	for _, eventSource := range discovery.EventSources {
+		code, _ := mapServiceNameToCode(eventSource)
+		if covered[code] {
+			continue
+		}
+		covered[code] = true
+		if name, ok := costExplorerServiceName(code); ok {
+			services = append(services, name)
+		} else {
+			services = append(services, eventSource)
+		}
+	}
+	return services
+}
+
+// markRecentChanges records on each inventory resource the latest CloudTrail write event that named it.
+// Events name resources by ID, name or ARN, so both sides are also matched by their last ARN segment.
+func markRecentChanges(services []ServiceMetadata, discovery cloudTrailDiscovery) {
+	for _, service := range services {
+		code, _ := mapServiceNameToCode(service.ServiceName)
+		changes := discovery.Changes[code]
+		if len(changes) == 0 {
+			continue
+		}
+		service.MetaData["recent_changes"] = changes
+
+		latest := make(map[string]map[string]interface{})
+		for _, change := range changes {
+			resources, _ := change["resources"].([]string)
+			for _, name := range resources {
+				for _, key := range []string{name, lastARNSegment(name)} {
+					if _, ok := latest[key]; !ok && key != "" {
+						latest[key] = change
+					}
+				}
+			}
+		}
+
+		for _, resource := range service.Resources {
+			for _, key := range []string{resource.ResourceID, lastARNSegment(resource.ResourceID)} {
+				if change, ok := latest[key]; ok {
+					resource.MetaData["recent_change"] = change
+					break
+				}
+			}
+		}
+	}
+}
+
+func lastARNSegment(id string) string {
+	return id[strings.LastIndexAny(id, ":/")+1:]
+}
@@ -0,0 +1,60 @@
+package entity
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ctTypes "github.com/aws/aws-sdk-go-v2/service/cloudtrail/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCloudTrailDiscovery(t *testing.T) {
+	now := time.Now()
+	events := []ctTypes.Event{
+		{EventSource: aws.String("sqs.amazonaws.com"), EventName: aws.String("CreateQueue"), EventTime: aws.Time(now),
+			Resources: []ctTypes.Resource{{ResourceName: aws.String("https://sqs.us-east-1.amazonaws.com/123/jobs")}}},
+		{EventSource: aws.String("ec2.amazonaws.com"), EventName: aws.String("StopInstances"), EventTime: aws.Time(now),
+			Resources: []ctTypes.Resource{{ResourceName: aws.String("i-1")}}},
+		{EventSource: aws.String("ec2.amazonaws.com"), EventName: aws.String("RunInstances"), EventTime: aws.Time(now.Add(-time.Hour)),
+			Resources: []ctTypes.Resource{{ResourceName: aws.String("i-1")}}},
+		{EventSource: aws.String("logs.amazonaws.com"), EventName: aws.String("CreateLogGroup"), EventTime: aws.Time(now)},
+		{EventSource: aws.String("signin.amazonaws.com"), EventName: aws.String("ConsoleLogin"), EventTime: aws.Time(now)},
+	}
+
+	discovery := groupCloudTrailEvents(events)
+	assert.Equal(t, []string{"ec2.amazonaws.com", "logs.amazonaws.com", "sqs.amazonaws.com"}, discovery.EventSources)
+
+	// Discovered services are named as in Cost Explorer
+	services := mergeDiscoveredServices([]string{"Amazon Elastic Compute Cloud - Compute"}, discovery)
+	assert.Equal(t, []string{"Amazon Elastic Compute Cloud - Compute", "AmazonCloudWatch", "Amazon Simple Queue Service"}, services)
+
+	results := []ServiceMetadata{
+		{ServiceName: "Amazon Elastic Compute Cloud - Compute", MetaData: map[string]interface{}{}, Resources: []ResourceMetadata{
+			{ResourceID: "i-1", MetaData: map[string]interface{}{}},
+		}},
+		{ServiceName: "Amazon Simple Queue Service", MetaData: map[string]interface{}{}, Resources: []ResourceMetadata{
+			{ResourceID: "arn:aws:sqs:us-east-1:123:jobs", MetaData: map[string]interface{}{}},
+		}},
+	}
+	markRecentChanges(results, discovery)
+
+	assert.Len(t, results[0].MetaData["recent_changes"], 2)
+	change := results[0].Resources[0].MetaData["recent_change"].(map[string]interface{})
+	assert.Equal(t, "StopInstances", change["event_name"])
+	change = results[1].Resources[0].MetaData["recent_change"].(map[string]interface{})
+	assert.Equal(t, "CreateQueue", change["event_name"])
+}
+
+func TestEventSourcesHaveCostExplorerNames(t *testing.T) {
+	for name, code := range serviceNameCodes {
+		if !strings.HasSuffix(name, ".amazonaws.com") {
+			continue
+		}
+		ceName, ok := costExplorerServiceName(code)
+		assert.True(t, ok, name)
+		mapped, _ := mapServiceNameToCode(ceName)
+		assert.Equal(t, code, mapped, name)
+	}
+}
@@ -0,0 +1,95 @@
+package entity
+
+import (
+	"errors"
+	"strings"
+
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/smithy-go"
+)
+
+// Exit codes of inventory make
+const (
+	// Every collector succeeded
+	exitComplete = 0
+	// Nothing was collected or written, or any error with --strict
+	exitFailure = 1
+	// The inventory was written without the services or steps that failed
+	exitPartial = 2
+	// Credentials are missing or expired, or a call was denied
+	exitAuthError = 3
+)
+
+// API error codes AWS services return for missing, expired or insufficient credentials
+var authErrorCodes = map[string]bool{
+	"AccessDenied":                true,
+	"AccessDeniedException":       true,
+	"AuthFailure":                 true,
+	"AuthorizationError":          true,
+	"ExpiredToken":                true,
+	"ExpiredTokenException":       true,
+	"InvalidAccessKeyId":          true,
+	"InvalidClientTokenId":        true,
+	"MissingAuthenticationToken":  true,
+	"SignatureDoesNotMatch":       true,
+	"UnauthorizedOperation":       true,
+	"UnrecognizedClientException": true,
+}
+
+// InventoryError is a collector or collection step that failed, the services and links it would have added
+// are missing from the inventory
+type InventoryError struct {
+	ServiceName string `json:"service_name,omitempty"`
+	ServiceCode string `json:"service_code,omitempty"`
+	// Step of the collection, e.g. list_resources or cloudwatch_alarms
+	Step string `json:"step"`
+	// auth for credential and permission errors, error otherwise
+	Kind  string `json:"kind"`
+	Error string `json:"error"`
+}
+
+func newInventoryError(step, serviceName, serviceCode string, err error) InventoryError {
+	kind := "error"
+	if isAuthError(err) {
+		kind = "auth"
+	}
+	return InventoryError{
+		ServiceName: serviceName,
+		ServiceCode: serviceCode,
+		Step:        step,
+		Kind:        kind,
+		Error:       err.Error(),
+	}
+}
+
+// isAuthError reports whether an AWS call failed because of missing, expired or insufficient credentials
+func isAuthError(err error) bool {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) && authErrorCodes[apiErr.ErrorCode()] {
+		return true
+	}
+	var respErr *awshttp.ResponseError
+	if errors.As(err, &respErr) && (respErr.HTTPStatusCode() == 401 || respErr.HTTPStatusCode() == 403) {
+		return true
+	}
+	// The credential chain does not export its errors, every failure is wrapped by the credentials cache
+	return strings.Contains(err.Error(), "failed to refresh cached credentials")
+}
+
+// inventoryExitCode returns the exit code of a collection that finished with errs and collected services. Auth
+// errors take precedence so automation can tell expired credentials from a service outage.
+func inventoryExitCode(errs []InventoryError, collected int, strict bool) int {
+	if len(errs) == 0 {
+		return exitComplete
+	}
+	for _, e := range errs {
+		if e.Kind == "auth" {
+			return exitAuthError
+		}
+	}
+	// Every collector failed, there is nothing to write
+	if strict || collected == 0 {
+		return exitFailure
+	}
+	return exitPartial
+}
@@ -0,0 +1,40 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/aws/smithy-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInventoryExitCode(t *testing.T) {
+	denied := newInventoryError("list_resources", "Amazon Simple Storage Service", "s3",
+		fmt.Errorf("listing S3 buckets: %w", &smithy.GenericAPIError{Code: "AccessDenied", Message: "Access Denied"}))
+	expired := newInventoryError("collect", "", "",
+		errors.New("get identity: failed to refresh cached credentials, no EC2 IMDS role found"))
+	throttled := newInventoryError("cloudwatch_alarms", "", "cloudwatch", &smithy.GenericAPIError{Code: "Throttling"})
+	assert.Equal(t, "auth", denied.Kind)
+	assert.Equal(t, "auth", expired.Kind)
+	assert.Equal(t, "error", throttled.Kind)
+
+	assert.Equal(t, exitComplete, inventoryExitCode(nil, 0, false))
+	assert.Equal(t, exitPartial, inventoryExitCode([]InventoryError{throttled}, 3, false))
+	assert.Equal(t, exitFailure, inventoryExitCode([]InventoryError{throttled}, 3, true))
+	assert.Equal(t, exitAuthError, inventoryExitCode([]InventoryError{throttled, denied}, 3, false))
+	assert.Equal(t, exitAuthError, inventoryExitCode([]InventoryError{denied}, 3, true))
+	// Every collector failed
+	assert.Equal(t, exitFailure, inventoryExitCode([]InventoryError{throttled}, 0, false))
+	assert.Equal(t, exitAuthError, inventoryExitCode([]InventoryError{denied}, 0, false))
+}
+
+func TestPartialResourcesError(t *testing.T) {
+	err := error(&partialResourcesError{Step: "rds_clusters",
+		Err: fmt.Errorf("listing RDS clusters: %w", &smithy.GenericAPIError{Code: "AccessDenied"})})
+
+	var partial *partialResourcesError
+	assert.True(t, errors.As(err, &partial))
+	assert.Equal(t, "auth", newInventoryError(partial.Step, "Amazon Relational Database Service", "rds", partial.Err).Kind)
+	assert.EqualError(t, err, "listing RDS clusters: api error AccessDenied: ")
+}
@@ -0,0 +1,68 @@
+package entity
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/spf13/cobra"
+)
+
+type exportOptions struct {
+	Format       string
+	OwnerTags    []string
+	DefaultOwner string
+}
+
+func exportInventoryCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export inventory files to infrastructure as code and catalog formats",
+		Run: func(cmd *cobra.Command, args []string) {
+			in, _ := cmd.Flags().GetStringSlice("in")
+			out, _ := cmd.Flags().GetString("out")
+			format, _ := cmd.Flags().GetString("format")
+			ownerTags, _ := cmd.Flags().GetStringSlice("owner-tags")
+			defaultOwner, _ := cmd.Flags().GetString("default-owner")
+			log.Printf("Exporting inventory as %s to %s\n", format, out)
+			err := exportInventory(in, out, exportOptions{
+				Format:       format,
+				OwnerTags:    ownerTags,
+				DefaultOwner: defaultOwner,
+			})
+			if err != nil {
+				log.Printf("Error exporting inventory: %v\n", err)
+			}
+		},
+	}
+	cmd.Flags().StringSlice("in", nil, "Input paths of inventory files, one per account")
+	cmd.Flags().String("out", "", "Output directory for the exported files")
+	cmd.Flags().String("format", "terraform", "Export format: terraform or backstage")
+	cmd.Flags().StringSlice("owner-tags", []string{"owner", "team"}, "Tags holding the owner of a resource, in order of precedence (backstage)")
+	cmd.Flags().String("default-owner", "unknown", "Owner of resources without owner tags (backstage)")
+
+	cmd.MarkFlagRequired("in")
+	cmd.MarkFlagRequired("out")
+	cmd.MarkFlagFilename("in")
+	cmd.MarkFlagDirname("out")
+	return cmd
+}
+
+func exportInventory(in []string, out string, options exportOptions) error {
+	var services []ServiceMetadata
+	for _, path := range in {
+		inventory, err := readInventoryFile(path)
+		if err != nil {
+			return err
+		}
+		services = append(services, inventory...)
+	}
+
+	switch options.Format {
+	case "terraform":
+		return writeTerraformImports(services, out)
+	case "backstage":
+		return writeBackstageCatalog(services, out, options)
+	default:
+		return fmt.Errorf("unknown export format %q", options.Format)
+	}
+}
@@ -0,0 +1,361 @@
+package entity
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// Snapshot file names sort in the order they were taken
+const snapshotTimeFormat = "20060102T150405Z"
+
+// Metadata keys that change on every collection and would otherwise mark each resource as changed.
+// Collectors declare the keys they refresh on every run with addVolatileMetadataKeys.
+var volatileMetadataKeys = make(map[string]bool)
+
+// addVolatileMetadataKeys declares metadata keys ignored when comparing inventories, a key ending in * matches
+// every key with that prefix
+func addVolatileMetadataKeys(keys ...string) {
+	for _, key := range keys {
+		volatileMetadataKeys[key] = true
+	}
+}
+
+func isVolatileMetadataKey(key string) bool {
+	if volatileMetadataKeys[key] {
+		return true
+	}
+	for pattern := range volatileMetadataKeys {
+		if strings.HasSuffix(pattern, "*") && strings.HasPrefix(key, strings.TrimSuffix(pattern, "*")) {
+			return true
+		}
+	}
+	return false
+}
+
+// SnapshotEntry describes one snapshot in the index of a history directory
+type SnapshotEntry struct {
+	TakenAt       time.Time `json:"taken_at"`
+	File          string    `json:"file"`
+	ServiceCount  int       `json:"service_count"`
+	ResourceCount int       `json:"resource_count"`
+}
+
+// ResourceChange is a resource that appeared, changed or disappeared between two inventories
+type ResourceChange struct {
+	ServiceName string   `json:"service_name"`
+	ResourceID  string   `json:"resource_id"`
+	Change      string   `json:"change"`
+	Keys        []string `json:"keys,omitempty"`
+}
+
+func historyInventoryCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "history",
+		Short: "List the inventory snapshots of a history directory",
+		Run: func(cmd *cobra.Command, args []string) {
+			dir, _ := cmd.Flags().GetString("dir")
+			index, err := readSnapshotIndex(dir)
+			if err != nil {
+				log.Printf("Error reading history: %v\n", err)
+				os.Exit(exitFailure)
+			}
+			for _, entry := range index {
+				fmt.Printf("%s  %d services  %d resources  %s\n", entry.TakenAt.Format(time.RFC3339),
+					entry.ServiceCount, entry.ResourceCount, entry.File)
+			}
+		},
+	}
+	cmd.Flags().String("dir", "", "History directory written by inventory make --history")
+
+	cmd.MarkFlagRequired("dir")
+	cmd.MarkFlagDirname("dir")
+	return cmd
+}
+
+func showInventoryCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "show",
+		Short: "Reconstruct the inventory as it was at a point in time",
+		Run: func(cmd *cobra.Command, args []string) {
+			dir, _ := cmd.Flags().GetString("dir")
+			at, _ := cmd.Flags().GetString("at")
+			out, _ := cmd.Flags().GetString("out")
+			atTime, err := parseHistoryTime(at)
+			if err != nil {
+				log.Printf("Invalid time %s, use a date like 2026-09-01 or an RFC 3339 time\n", at)
+				os.Exit(exitFailure)
+			}
+
+			entry, services, err := readSnapshotAt(dir, atTime)
+			if err != nil {
+				log.Printf("Error reading history: %v\n", err)
+				os.Exit(exitFailure)
+			}
+			log.Printf("Writing snapshot taken at %s to %s\n", entry.TakenAt.Format(time.RFC3339), out)
+			if err := writeToJsonFile(services, out); err != nil {
+				log.Printf("Error writing inventory: %v\n", err)
+				os.Exit(exitFailure)
+			}
+		},
+	}
+	cmd.Flags().String("dir", "", "History directory written by inventory make --history")
+	cmd.Flags().String("at", "", "Date or time to show, the latest snapshot taken at or before it is used")
+	cmd.Flags().String("out", "", "Output path for the inventory file")
+
+	cmd.MarkFlagRequired("dir")
+	cmd.MarkFlagRequired("at")
+	cmd.MarkFlagRequired("out")
+	cmd.MarkFlagDirname("dir")
+	cmd.MarkFlagFilename("out")
+	return cmd
+}
+
+func timelineInventoryCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "timeline <resource-id>",
+		Short: "Show when a resource appeared, changed or disappeared",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			dir, _ := cmd.Flags().GetString("dir")
+			events, err := resourceTimeline(dir, args[0])
+			if err != nil {
+				log.Printf("Error reading history: %v\n", err)
+				os.Exit(exitFailure)
+			}
+			if len(events) == 0 {
+				log.Printf("Resource %s is not in any snapshot\n", args[0])
+			}
+			for _, event := range events {
+				line := fmt.Sprintf("%s  %-11s %s", event.TakenAt.Format(time.RFC3339), event.Change, event.ServiceName)
+				if len(event.Keys) > 0 {
+					line += fmt.Sprintf(" %v", event.Keys)
+				}
+				fmt.Println(line)
+			}
+		},
+	}
+	cmd.Flags().String("dir", "", "History directory written by inventory make --history")
+
+	cmd.MarkFlagRequired("dir")
+	cmd.MarkFlagDirname("dir")
+	return cmd
+}
+
+// saveSnapshot adds a compressed snapshot of the inventory to the history directory and records it in the index.
+// Existing snapshots are never rewritten.
+func saveSnapshot(dir string, services []ServiceMetadata, takenAt time.Time) error {
+	snapshotDir := filepath.Join(dir, "snapshots")
+	if err := os.MkdirAll(snapshotDir, os.ModePerm); err != nil {
+		return err
+	}
+
+	takenAt = takenAt.UTC()
+	name := filepath.Join("snapshots", takenAt.Format(snapshotTimeFormat)+".json.gz")
+	file, err := os.OpenFile(filepath.Join(dir, name), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := gzip.NewWriter(file)
+	if err := json.NewEncoder(writer).Encode(services); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	index, err := readSnapshotIndex(dir)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	resourceCount := 0
+	for _, service := range services {
+		resourceCount += len(service.Resources)
+	}
+	index = append(index, SnapshotEntry{
+		TakenAt:       takenAt,
+		File:          name,
+		ServiceCount:  len(services),
+		ResourceCount: resourceCount,
+	})
+
+	// The index is replaced by renaming, a crash while writing it leaves the previous index in place
+	path := filepath.Join(dir, "index.json")
+	if err := writeToJsonFile(index, path+".tmp"); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// readSnapshotIndex returns the snapshots of a history directory, oldest first
+func readSnapshotIndex(dir string) ([]SnapshotEntry, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "index.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var index []SnapshotEntry
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(index, func(i, j int) bool {
+		return index[i].TakenAt.Before(index[j].TakenAt)
+	})
+	return index, nil
+}
+
+func readSnapshot(dir string, entry SnapshotEntry) ([]ServiceMetadata, error) {
+	file, err := os.Open(filepath.Join(dir, entry.File))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	var services []ServiceMetadata
+	if err := json.NewDecoder(reader).Decode(&services); err != nil {
+		return nil, err
+	}
+	return services, nil
+}
+
+// readSnapshotAt returns the latest snapshot taken at or before the given time
+func readSnapshotAt(dir string, at time.Time) (SnapshotEntry, []ServiceMetadata, error) {
+	index, err := readSnapshotIndex(dir)
+	if err != nil {
+		return SnapshotEntry{}, nil, err
+	}
+
+	for i := len(index) - 1; i >= 0; i-- {
+		if !index[i].TakenAt.After(at) {
+			services, err := readSnapshot(dir, index[i])
+			return index[i], services, err
+		}
+	}
+	return SnapshotEntry{}, nil, fmt.Errorf("no snapshot taken at or before %s", at.Format(time.RFC3339))
+}
+
+// parseHistoryTime accepts an RFC 3339 time, or a date meaning the end of that day in UTC
+func parseHistoryTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return date.Add(24*time.Hour - time.Nanosecond), nil
+}
+
+// timelineEvent is a change of one resource found between two consecutive snapshots
+type timelineEvent struct {
+	ResourceChange
+	TakenAt time.Time
+}
+
+// resourceTimeline walks the snapshots in order and reports each change of the resource
+func resourceTimeline(dir, resourceID string) ([]timelineEvent, error) {
+	index, err := readSnapshotIndex(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []timelineEvent
+	var previous []ServiceMetadata
+	for _, entry := range index {
+		services, err := readSnapshot(dir, entry)
+		if err != nil {
+			return nil, err
+		}
+		for _, change := range diffInventories(previous, services) {
+			if change.ResourceID == resourceID {
+				events = append(events, timelineEvent{ResourceChange: change, TakenAt: entry.TakenAt})
+			}
+		}
+		previous = services
+	}
+	return events, nil
+}
+
+// diffInventories returns the resources that appeared, disappeared or changed from one inventory to the next.
+// Resources are matched by service and resource ID, volatile metadata keys are ignored.
+func diffInventories(before, after []ServiceMetadata) []ResourceChange {
+	type resourceKey struct {
+		serviceName string
+		resourceID  string
+	}
+	index := func(services []ServiceMetadata) map[resourceKey]map[string]interface{} {
+		resources := make(map[resourceKey]map[string]interface{})
+		for _, service := range services {
+			for _, resource := range service.Resources {
+				resources[resourceKey{service.ServiceName, resource.ResourceID}] = resource.MetaData
+			}
+		}
+		return resources
+	}
+	beforeResources := index(before)
+	afterResources := index(after)
+
+	var changes []ResourceChange
+	for key, metadata := range afterResources {
+		previous, ok := beforeResources[key]
+		if !ok {
+			changes = append(changes, ResourceChange{ServiceName: key.serviceName, ResourceID: key.resourceID, Change: "appeared"})
+			continue
+		}
+		if keys := changedMetadataKeys(previous, metadata); len(keys) > 0 {
+			changes = append(changes, ResourceChange{ServiceName: key.serviceName, ResourceID: key.resourceID, Change: "changed", Keys: keys})
+		}
+	}
+	for key := range beforeResources {
+		if _, ok := afterResources[key]; !ok {
+			changes = append(changes, ResourceChange{ServiceName: key.serviceName, ResourceID: key.resourceID, Change: "disappeared"})
+		}
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool {
+		if changes[i].ServiceName != changes[j].ServiceName {
+			return changes[i].ServiceName < changes[j].ServiceName
+		}
+		return changes[i].ResourceID < changes[j].ResourceID
+	})
+	return changes
+}
+
+// changedMetadataKeys compares metadata after a JSON round trip, so collected and stored inventories compare equal
+func changedMetadataKeys(before, after map[string]interface{}) []string {
+	var normalizedBefore, normalizedAfter map[string]interface{}
+	if decodeMetadata(before, &normalizedBefore) != nil || decodeMetadata(after, &normalizedAfter) != nil {
+		return nil
+	}
+
+	keys := make(map[string]bool)
+	for key, value := range normalizedAfter {
+		if !isVolatileMetadataKey(key) && !reflect.DeepEqual(normalizedBefore[key], value) {
+			keys[key] = true
+		}
+	}
+	for key := range normalizedBefore {
+		if _, ok := normalizedAfter[key]; !ok && !isVolatileMetadataKey(key) {
+			keys[key] = true
+		}
+	}
+	return sortedKeys(keys)
+}
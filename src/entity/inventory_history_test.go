@@ -0,0 +1,108 @@
+package entity
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffInventories(t *testing.T) {
+	before := []ServiceMetadata{
+		{ServiceName: "Amazon Elastic Compute Cloud - Compute", Resources: []ResourceMetadata{
+			{ResourceID: "i-1", MetaData: map[string]interface{}{"instance_type": "t3.micro", "recent_change": "a"}},
+			{ResourceID: "i-2", MetaData: map[string]interface{}{"instance_type": "t3.micro"}},
+			{ResourceID: "i-3", MetaData: map[string]interface{}{"instance_type": "t3.micro"}},
+		}},
+	}
+	after := []ServiceMetadata{
+		{ServiceName: "Amazon Elastic Compute Cloud - Compute", Resources: []ResourceMetadata{
+			{ResourceID: "i-1", MetaData: map[string]interface{}{"instance_type": "t3.micro", "recent_change": "b"}},
+			{ResourceID: "i-2", MetaData: map[string]interface{}{"instance_type": "t3.large"}},
+			{ResourceID: "i-4", MetaData: map[string]interface{}{"instance_type": "t3.micro"}},
+		}},
+	}
+
+	changes := diffInventories(before, after)
+
+	assert.Equal(t, []ResourceChange{
+		{ServiceName: "Amazon Elastic Compute Cloud - Compute", ResourceID: "i-2", Change: "changed", Keys: []string{"instance_type"}},
+		{ServiceName: "Amazon Elastic Compute Cloud - Compute", ResourceID: "i-3", Change: "disappeared"},
+		{ServiceName: "Amazon Elastic Compute Cloud - Compute", ResourceID: "i-4", Change: "appeared"},
+	}, changes)
+}
+
+func TestSnapshotHistory(t *testing.T) {
+	dir := t.TempDir()
+	first := time.Date(2026, 9, 1, 10, 0, 0, 0, time.UTC)
+	second := time.Date(2026, 9, 2, 10, 0, 0, 0, time.UTC)
+	service := func(metadata map[string]interface{}) []ServiceMetadata {
+		return []ServiceMetadata{{ServiceName: "AWS Lambda", Resources: []ResourceMetadata{
+			{ResourceID: "handler", MetaData: metadata},
+		}}}
+	}
+
+	assert.NoError(t, saveSnapshot(dir, service(map[string]interface{}{"runtime": "python3.9"}), first))
+	assert.NoError(t, saveSnapshot(dir, service(map[string]interface{}{"runtime": "python3.12"}), second))
+	_, err := os.Stat(filepath.Join(dir, "index.json.tmp"))
+	assert.True(t, os.IsNotExist(err))
+
+	at, err := parseHistoryTime("2026-09-01")
+	assert.NoError(t, err)
+	entry, services, err := readSnapshotAt(dir, at)
+	assert.NoError(t, err)
+	assert.Equal(t, first, entry.TakenAt)
+	assert.Equal(t, "python3.9", services[0].Resources[0].MetaData["runtime"])
+
+	_, _, err = readSnapshotAt(dir, first.Add(-time.Hour))
+	assert.Error(t, err)
+
+	events, err := resourceTimeline(dir, "handler")
+	assert.NoError(t, err)
+	assert.Len(t, events, 2)
+	assert.Equal(t, "appeared", events[0].Change)
+	assert.Equal(t, "changed", events[1].Change)
+	assert.Equal(t, []string{"runtime"}, events[1].Keys)
+}
+
+func TestDiffInventoriesIgnoresVolatileMetadata(t *testing.T) {
+	queue := func(messages string) []ServiceMetadata {
+		attributes := map[string]string{
+			"QueueArn":                              "arn:aws:sqs:eu-west-1:123:orders",
+			"VisibilityTimeout":                     "30",
+			"ApproximateNumberOfMessages":           messages,
+			"ApproximateNumberOfMessagesNotVisible": messages,
+		}
+		return []ServiceMetadata{{ServiceName: "Amazon Simple Queue Service", Resources: []ResourceMetadata{
+			{ResourceID: attributes["QueueArn"], MetaData: sqsQueueMetadata("https://sqs.eu-west-1.amazonaws.com/123/orders", attributes)},
+		}}}
+	}
+	assert.Empty(t, diffInventories(queue("0"), queue("250")))
+
+	health := "up"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/v1/targets" {
+			fmt.Fprintf(w, `{"status":"success","data":{"activeTargets":[{"labels":{"instance":"node-a:9100","job":"node"},
+				"health":%q,"lastScrape":%q,"lastScrapeDuration":0.01}]}}`, health, time.Now().Format(time.RFC3339Nano))
+			return
+		}
+		w.Write([]byte(`{"status":"success","data":["up"]}`))
+	}))
+	defer server.Close()
+
+	before, err := listPrometheusJobs(server.URL)
+	assert.NoError(t, err)
+	health = "down"
+	after, err := listPrometheusJobs(server.URL)
+	assert.NoError(t, err)
+	assert.Empty(t, diffInventories(before, after))
+
+	assert.True(t, isVolatileMetadataKey("instances_arn:aws:elasticloadbalancing:eu-west-1:123:targetgroup/web/abc"))
+	assert.True(t, isVolatileMetadataKey("dimension_set_count"))
+	assert.False(t, isVolatileMetadataKey("instance_type"))
+}
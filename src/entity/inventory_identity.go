@@ -0,0 +1,392 @@
+package entity
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	"github.com/aws/aws-sdk-go-v2/service/cognitoidentityprovider"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	kmsTypes "github.com/aws/aws-sdk-go-v2/service/kms/types"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/sesv2"
+)
+
+// User counts, secret access and sending quotas change between collections
+func init() {
+	addVolatileMetadataKeys("estimated_number_of_users", "last_accessed", "sent_last_24_hours")
+}
+
+// listCognitoUserPools retrieves Cognito user pools
+func listCognitoUserPools(cfg aws.Config) ([]ResourceMetadata, error) {
+	svc := cognitoidentityprovider.NewFromConfig(cfg)
+
+	var resources []ResourceMetadata
+	paginator := cognitoidentityprovider.NewListUserPoolsPaginator(svc, &cognitoidentityprovider.ListUserPoolsInput{
+		MaxResults: aws.Int32(60),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return nil, err
+		}
+
+		for _, pool := range page.UserPools {
+			id := aws.ToString(pool.Id)
+			metadata := map[string]interface{}{
+				"name":          aws.ToString(pool.Name),
+				"creation_date": pool.CreationDate,
+			}
+
+			desc, err := svc.DescribeUserPool(context.TODO(), &cognitoidentityprovider.DescribeUserPoolInput{
+				UserPoolId: pool.Id,
+			})
+			if err != nil {
+				log.Printf("Error describing Cognito user pool: %s\n", id)
+			} else {
+				userPool := desc.UserPool
+				metadata["estimated_number_of_users"] = userPool.EstimatedNumberOfUsers
+				metadata["mfa_configuration"] = userPool.MfaConfiguration
+				metadata["deletion_protection"] = userPool.DeletionProtection
+				metadata["domain"] = aws.ToString(userPool.Domain)
+				metadata["tags"] = userPool.UserPoolTags
+			}
+			setCloudWatchRef(metadata, "AWS/Cognito", map[string]string{"UserPool": id})
+
+			resources = append(resources, ResourceMetadata{
+				ResourceID: id,
+				MetaData:   metadata,
+			})
+		}
+	}
+
+	return resources, nil
+}
+
+// listKMSKeys retrieves KMS keys and their rotation status. Key policies are not collected.
+func listKMSKeys(cfg aws.Config) ([]ResourceMetadata, error) {
+	svc := kms.NewFromConfig(cfg)
+
+	var resources []ResourceMetadata
+	paginator := kms.NewListKeysPaginator(svc, &kms.ListKeysInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return nil, err
+		}
+
+		for _, key := range page.Keys {
+			desc, err := svc.DescribeKey(context.TODO(), &kms.DescribeKeyInput{
+				KeyId: key.KeyId,
+			})
+			if err != nil {
+				log.Printf("Error describing KMS key: %s\n", aws.ToString(key.KeyId))
+				continue
+			}
+			keyMetadata := desc.KeyMetadata
+
+			metadata := map[string]interface{}{
+				"key_id":      aws.ToString(keyMetadata.KeyId),
+				"description": aws.ToString(keyMetadata.Description),
+				"key_manager": keyMetadata.KeyManager,
+				"key_state":   keyMetadata.KeyState,
+				"key_spec":    keyMetadata.KeySpec,
+				"key_usage":   keyMetadata.KeyUsage,
+				"enabled":     keyMetadata.Enabled,
+			}
+
+			// Rotation can only be read and configured on customer managed keys
+			if keyMetadata.KeyManager == kmsTypes.KeyManagerTypeCustomer {
+				rotation, err := svc.GetKeyRotationStatus(context.TODO(), &kms.GetKeyRotationStatusInput{
+					KeyId: key.KeyId,
+				})
+				if err == nil {
+					metadata["rotation_enabled"] = rotation.KeyRotationEnabled
+					if rotation.RotationPeriodInDays != nil {
+						metadata["rotation_period_days"] = *rotation.RotationPeriodInDays
+					}
+				}
+			}
+
+			resources = append(resources, ResourceMetadata{
+				ResourceID: aws.ToString(key.KeyArn),
+				MetaData:   metadata,
+			})
+		}
+	}
+
+	return resources, nil
+}
+
+// listSecrets retrieves Secrets Manager secret metadata and rotation. Secret values are never read.
+func listSecrets(cfg aws.Config) ([]ResourceMetadata, error) {
+	svc := secretsmanager.NewFromConfig(cfg)
+
+	var resources []ResourceMetadata
+	paginator := secretsmanager.NewListSecretsPaginator(svc, &secretsmanager.ListSecretsInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return nil, err
+		}
+
+		for _, secret := range page.SecretList {
+			tags := make(map[string]string)
+			for _, tag := range secret.Tags {
+				if tag.Key != nil && tag.Value != nil {
+					tags[*tag.Key] = *tag.Value
+				}
+			}
+
+			metadata := map[string]interface{}{
+				"name":             aws.ToString(secret.Name),
+				"description":      aws.ToString(secret.Description),
+				"kms_key_id":       aws.ToString(secret.KmsKeyId),
+				"rotation_enabled": aws.ToBool(secret.RotationEnabled),
+				"owning_service":   aws.ToString(secret.OwningService),
+				"created_date":     secret.CreatedDate,
+				"last_changed":     secret.LastChangedDate,
+				"last_accessed":    secret.LastAccessedDate,
+				"last_rotated":     secret.LastRotatedDate,
+				"next_rotation":    secret.NextRotationDate,
+				"tags":             tags,
+			}
+			if secret.RotationRules != nil && secret.RotationRules.AutomaticallyAfterDays != nil {
+				metadata["rotation_days"] = *secret.RotationRules.AutomaticallyAfterDays
+			}
+
+			resources = append(resources, ResourceMetadata{
+				ResourceID: aws.ToString(secret.ARN),
+				MetaData:   metadata,
+			})
+		}
+	}
+
+	return resources, nil
+}
+
+// listSESIdentities retrieves SES email identities and the account's sending quota
+func listSESIdentities(cfg aws.Config) ([]ResourceMetadata, error) {
+	svc := sesv2.NewFromConfig(cfg)
+
+	var resources []ResourceMetadata
+	paginator := sesv2.NewListEmailIdentitiesPaginator(svc, &sesv2.ListEmailIdentitiesInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return nil, err
+		}
+
+		for _, identity := range page.EmailIdentities {
+			resources = append(resources, ResourceMetadata{
+				ResourceID: aws.ToString(identity.IdentityName),
+				MetaData: map[string]interface{}{
+					"resource_type":       "identity",
+					"identity_type":       identity.IdentityType,
+					"sending_enabled":     identity.SendingEnabled,
+					"verification_status": identity.VerificationStatus,
+				},
+			})
+		}
+	}
+
+	account, err := svc.GetAccount(context.TODO(), &sesv2.GetAccountInput{})
+	if err != nil {
+		return resources, &partialResourcesError{Step: "ses_account", Err: fmt.Errorf("getting SES account details: %w", err)}
+	}
+
+	metadata := map[string]interface{}{
+		"resource_type":     "sending_quota",
+		"production_access": account.ProductionAccessEnabled,
+		"sending_enabled":   account.SendingEnabled,
+	}
+	if account.SendQuota != nil {
+		metadata["max_24_hour_send"] = account.SendQuota.Max24HourSend
+		metadata["max_send_rate"] = account.SendQuota.MaxSendRate
+		metadata["sent_last_24_hours"] = account.SendQuota.SentLast24Hours
+	}
+	resources = append(resources, ResourceMetadata{
+		ResourceID: "sending-quota",
+		MetaData:   metadata,
+	})
+
+	return resources, nil
+}
+
+// listCloudFormationStacks retrieves CloudFormation stacks with their drift status and the resources they own
+func listCloudFormationStacks(cfg aws.Config) ([]ResourceMetadata, error) {
+	svc := cloudformation.NewFromConfig(cfg)
+
+	var resources []ResourceMetadata
+	paginator := cloudformation.NewDescribeStacksPaginator(svc, &cloudformation.DescribeStacksInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return nil, err
+		}
+
+		for _, stack := range page.Stacks {
+			name := aws.ToString(stack.StackName)
+
+			tags := make(map[string]string)
+			for _, tag := range stack.Tags {
+				if tag.Key != nil && tag.Value != nil {
+					tags[*tag.Key] = *tag.Value
+				}
+			}
+
+			metadata := map[string]interface{}{
+				"name":          name,
+				"status":        stack.StackStatus,
+				"description":   aws.ToString(stack.Description),
+				"creation_time": stack.CreationTime,
+				"last_updated":  stack.LastUpdatedTime,
+				"tags":          tags,
+				"resources":     getStackResources(svc, name),
+			}
+			if stack.DriftInformation != nil {
+				metadata["drift_status"] = stack.DriftInformation.StackDriftStatus
+				metadata["drift_last_checked"] = stack.DriftInformation.LastCheckTimestamp
+			}
+			if stack.ParentId != nil {
+				metadata["parent_stack_id"] = *stack.ParentId
+			}
+
+			resources = append(resources, ResourceMetadata{
+				ResourceID: aws.ToString(stack.StackId),
+				MetaData:   metadata,
+			})
+		}
+	}
+
+	return resources, nil
+}
+
+func getStackResources(svc *cloudformation.Client, stackName string) []map[string]interface{} {
+	var stackResources []map[string]interface{}
+	paginator := cloudformation.NewListStackResourcesPaginator(svc, &cloudformation.ListStackResourcesInput{
+		StackName: aws.String(stackName),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return stackResources
+		}
+		for _, resource := range page.StackResourceSummaries {
+			stackResources = append(stackResources, map[string]interface{}{
+				"logical_id":  aws.ToString(resource.LogicalResourceId),
+				"physical_id": aws.ToString(resource.PhysicalResourceId),
+				"type":        aws.ToString(resource.ResourceType),
+			})
+		}
+	}
+	return stackResources
+}
+
+// Inventory service codes of the resources each CloudFormation service namespace creates, e.g. the EC2 of
+// AWS::EC2::Instance. Resources of other namespaces are not matched with the inventory.
+var cloudFormationServiceCodes = map[string][]string{
+	"ApiGateway":             {"apigateway"},
+	"ApiGatewayV2":           {"apigateway"},
+	"CloudFront":             {"cloudfront"},
+	"CloudWatch":             {"cloudwatch"},
+	"Cognito":                {"cognito"},
+	"DynamoDB":               {"dynamodb"},
+	"EC2":                    {"ec2", "vpc", "amazonebs"},
+	"ECS":                    {"ecs", "fargate"},
+	"EFS":                    {"efs"},
+	"EKS":                    {"eks"},
+	"ElasticLoadBalancingV2": {"elb"},
+	"EMR":                    {"emr"},
+	"GlobalAccelerator":      {"globalaccelerator"},
+	"Glue":                   {"glue"},
+	"Kinesis":                {"kinesis"},
+	"KMS":                    {"kms"},
+	"Lambda":                 {"lambda"},
+	"Logs":                   {"cloudwatch"},
+	"RDS":                    {"rds", "aurora"},
+	"Redshift":               {"redshift"},
+	"Route53":                {"route53"},
+	"S3":                     {"s3"},
+	"SageMaker":              {"sagemaker"},
+	"SecretsManager":         {"secretsmanager"},
+	"SES":                    {"ses"},
+	"SNS":                    {"sns"},
+	"SQS":                    {"sqs"},
+	"StepFunctions":          {"stepfunctions"},
+}
+
+// attachStackMembership records on every inventory resource the CloudFormation stack that owns it.
+// Physical IDs are names, IDs, ARNs or URLs depending on the resource type, and are only matched with resources
+// of the service that creates that type, so an ECS cluster named web does not claim a load balancer named web.
+func attachStackMembership(services []ServiceMetadata, stacks []ResourceMetadata) {
+	owners := make(map[string]string)
+	for _, stack := range stacks {
+		name := metadataString(stack.MetaData, "name")
+		for _, resource := range stackResources(stack.MetaData) {
+			physicalID := metadataString(resource, "physical_id")
+			if physicalID == "" {
+				continue
+			}
+			for _, code := range stackResourceServiceCodes(metadataString(resource, "type")) {
+				owners[code+" "+physicalID] = name
+			}
+		}
+	}
+	if len(owners) == 0 {
+		return
+	}
+
+	for _, service := range services {
+		code, _ := mapServiceNameToCode(service.ServiceName)
+		for _, resource := range service.Resources {
+			if stack, ok := findStackOwner(owners, code, resource); ok {
+				resource.MetaData["cloudformation_stack"] = stack
+			}
+		}
+	}
+}
+
+// stackResourceServiceCodes returns the inventory service codes of a CloudFormation resource type
+func stackResourceServiceCodes(resourceType string) []string {
+	parts := strings.Split(resourceType, "::")
+	if len(parts) != 3 || parts[0] != "AWS" {
+		return nil
+	}
+	return cloudFormationServiceCodes[parts[1]]
+}
+
+func stackResources(metadata map[string]interface{}) []map[string]interface{} {
+	switch resources := metadata["resources"].(type) {
+	case []map[string]interface{}:
+		return resources
+	case []interface{}:
+		var stackResources []map[string]interface{}
+		for _, resource := range resources {
+			if r, ok := resource.(map[string]interface{}); ok {
+				stackResources = append(stackResources, r)
+			}
+		}
+		return stackResources
+	}
+	return nil
+}
+
+func findStackOwner(owners map[string]string, code string, resource ResourceMetadata) (string, bool) {
+	candidates := []string{resource.ResourceID, metadataString(resource.MetaData, "url")}
+
+	// ARNs end with the resource name or ID that CloudFormation reports
+	if i := strings.LastIndexAny(resource.ResourceID, ":/"); i >= 0 {
+		candidates = append(candidates, resource.ResourceID[i+1:])
+	}
+
+	for _, candidate := range candidates {
+		if stack, ok := owners[code+" "+candidate]; ok && candidate != "" {
+			return stack, true
+		}
+	}
+	return "", false
+}
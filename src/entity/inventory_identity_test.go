@@ -0,0 +1,51 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAttachStackMembership(t *testing.T) {
+	stacks := []ResourceMetadata{
+		{ResourceID: "arn:aws:cloudformation:us-east-1:123:stack/app/abc", MetaData: map[string]interface{}{
+			"name": "app",
+			"resources": []map[string]interface{}{
+				{"logical_id": "Instance", "physical_id": "i-1", "type": "AWS::EC2::Instance"},
+				{"logical_id": "Queue", "physical_id": "https://sqs.us-east-1.amazonaws.com/123/jobs", "type": "AWS::SQS::Queue"},
+				{"logical_id": "Topic", "physical_id": "arn:aws:sns:us-east-1:123:alerts", "type": "AWS::SNS::Topic"},
+				{"logical_id": "Cluster", "physical_id": "web", "type": "AWS::ECS::Cluster"},
+			},
+		}},
+	}
+	services := []ServiceMetadata{
+		{ServiceName: "Amazon Elastic Compute Cloud - Compute", Resources: []ResourceMetadata{
+			{ResourceID: "i-1", MetaData: map[string]interface{}{}},
+			{ResourceID: "i-2", MetaData: map[string]interface{}{}},
+		}},
+		{ServiceName: "Amazon Simple Queue Service", Resources: []ResourceMetadata{
+			{ResourceID: "arn:aws:sqs:us-east-1:123:jobs", MetaData: map[string]interface{}{
+				"url": "https://sqs.us-east-1.amazonaws.com/123/jobs",
+			}},
+		}},
+		{ServiceName: "Amazon Simple Notification Service", Resources: []ResourceMetadata{
+			{ResourceID: "arn:aws:sns:us-east-1:123:alerts", MetaData: map[string]interface{}{}},
+		}},
+		{ServiceName: "Amazon Elastic Container Service", Resources: []ResourceMetadata{
+			{ResourceID: "arn:aws:ecs:us-east-1:123:cluster/web", MetaData: map[string]interface{}{}},
+		}},
+		{ServiceName: "Amazon Elastic Load Balancing", Resources: []ResourceMetadata{
+			{ResourceID: "arn:aws:elasticloadbalancing:us-east-1:123:loadbalancer/app/web", MetaData: map[string]interface{}{}},
+		}},
+	}
+
+	attachStackMembership(services, stacks)
+
+	assert.Equal(t, "app", services[0].Resources[0].MetaData["cloudformation_stack"])
+	assert.NotContains(t, services[0].Resources[1].MetaData, "cloudformation_stack")
+	assert.Equal(t, "app", services[1].Resources[0].MetaData["cloudformation_stack"])
+	assert.Equal(t, "app", services[2].Resources[0].MetaData["cloudformation_stack"])
+	assert.Equal(t, "app", services[3].Resources[0].MetaData["cloudformation_stack"])
+	// The cluster named web does not make its stack the owner of other resources named web
+	assert.NotContains(t, services[4].Resources[0].MetaData, "cloudformation_stack")
+}
@@ -0,0 +1,320 @@
+package entity
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/spf13/cobra"
+)
+
+// Deprecation dates of Lambda runtimes, override with inventory lambda-runtimes --table when AWS announces new ones
+//
+//go:embed lambda_runtimes.json
+var lambdaRuntimesTable []byte
+
+// LambdaRuntimeEOL is the date after which AWS no longer applies security patches to a runtime
+type LambdaRuntimeEOL struct {
+	Runtime         string `json:"runtime"`
+	DeprecationDate string `json:"deprecation_date"`
+}
+
+// LambdaRuntimeItem is a function on a deprecated or soon to be deprecated runtime
+type LambdaRuntimeItem struct {
+	FunctionName    string `json:"function_name"`
+	Runtime         string `json:"runtime"`
+	Status          string `json:"status"`
+	DeprecationDate string `json:"deprecation_date"`
+	DaysRemaining   int    `json:"days_remaining"`
+}
+
+func lambdaRuntimesInventoryCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "lambda-runtimes",
+		Short: "Report Lambda functions on deprecated or soon to be deprecated runtimes",
+		Run: func(cmd *cobra.Command, args []string) {
+			in, _ := cmd.Flags().GetString("in")
+			out, _ := cmd.Flags().GetString("out")
+			table, _ := cmd.Flags().GetString("table")
+			within, _ := cmd.Flags().GetInt("within")
+			log.Printf("Creating Lambda runtime report at %s\n", out)
+			err := makeLambdaRuntimeReport(in, out, table, within, awsConfigOptionsFromFlags(cmd))
+			if err != nil {
+				log.Printf("Error creating Lambda runtime report: %v\n", err)
+			}
+		},
+	}
+	cmd.Flags().String("in", "", "Inventory file to read functions from, functions are listed from AWS when omitted")
+	cmd.Flags().String("out", "", "Output path for the runtime report")
+	cmd.Flags().String("table", "", "JSON file with runtime deprecation dates replacing the built-in table")
+	cmd.Flags().Int("within", 180, "Report runtimes deprecated within this many days")
+	addAWSConfigFlags(cmd)
+
+	cmd.MarkFlagRequired("out")
+	cmd.MarkFlagFilename("in")
+	cmd.MarkFlagFilename("out")
+	cmd.MarkFlagFilename("table")
+	return cmd
+}
+
+func makeLambdaRuntimeReport(in, out, table string, within int, awsOptions awsConfigOptions) error {
+	eolTable, err := loadLambdaRuntimeTable(table)
+	if err != nil {
+		return err
+	}
+
+	var functions []ResourceMetadata
+	if in != "" {
+		services, err := readInventoryFile(in)
+		if err != nil {
+			return err
+		}
+		for _, service := range services {
+			if code, _ := mapServiceNameToCode(service.ServiceName); code == "lambda" {
+				functions = append(functions, service.Resources...)
+			}
+		}
+	} else {
+		cfg, err := loadAWSConfig(awsOptions)
+		if err != nil {
+			return err
+		}
+		functions, err = listLambdaFunctions(cfg)
+		if err != nil {
+			return err
+		}
+	}
+
+	items := findDeprecatedRuntimes(functions, eolTable, time.Now(), within)
+	log.Printf("Found %d functions on deprecated or soon to be deprecated runtimes\n", len(items))
+	return writeToJsonFile(items, out)
+}
+
+// loadLambdaRuntimeTable reads the deprecation table from a file, or the built-in one when no file is given
+func loadLambdaRuntimeTable(path string) ([]LambdaRuntimeEOL, error) {
+	data := lambdaRuntimesTable
+	if path != "" {
+		var err error
+		data, err = os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var table []LambdaRuntimeEOL
+	if err := json.Unmarshal(data, &table); err != nil {
+		return nil, err
+	}
+	return table, nil
+}
+
+func findDeprecatedRuntimes(functions []ResourceMetadata, table []LambdaRuntimeEOL, now time.Time, within int) []LambdaRuntimeItem {
+	deprecationDates := make(map[string]time.Time)
+	for _, entry := range table {
+		date, err := time.Parse("2006-01-02", entry.DeprecationDate)
+		if err != nil {
+			log.Printf("Invalid deprecation date for runtime %s: %s\n", entry.Runtime, entry.DeprecationDate)
+			continue
+		}
+		deprecationDates[entry.Runtime] = date
+	}
+
+	items := []LambdaRuntimeItem{}
+	for _, function := range functions {
+		// Container image functions have no managed runtime
+		runtime := metadataString(function.MetaData, "runtime")
+		date, ok := deprecationDates[runtime]
+		if !ok {
+			continue
+		}
+
+		daysRemaining := int(date.Sub(now).Hours() / 24)
+		status := "deprecated"
+		if date.After(now) {
+			if daysRemaining > within {
+				continue
+			}
+			status = "deprecating_soon"
+		}
+
+		items = append(items, LambdaRuntimeItem{
+			FunctionName:    function.ResourceID,
+			Runtime:         runtime,
+			Status:          status,
+			DeprecationDate: date.Format("2006-01-02"),
+			DaysRemaining:   daysRemaining,
+		})
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].DaysRemaining < items[j].DaysRemaining
+	})
+	return items
+}
+
+// listLambdaFunctions retrieves Lambda functions with their configuration, versions, aliases, concurrency
+// and event source mappings
+func listLambdaFunctions(cfg aws.Config) ([]ResourceMetadata, error) {
+	svc := lambda.NewFromConfig(cfg)
+
+	eventSourceMappings := getEventSourceMappings(svc)
+
+	var resources []ResourceMetadata
+	paginator := lambda.NewListFunctionsPaginator(svc, &lambda.ListFunctionsInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return nil, err
+		}
+
+		for _, function := range page.Functions {
+			name := aws.ToString(function.FunctionName)
+
+			var layers []map[string]interface{}
+			for _, layer := range function.Layers {
+				layers = append(layers, map[string]interface{}{
+					"arn":       aws.ToString(layer.Arn),
+					"code_size": layer.CodeSize,
+				})
+			}
+
+			metadata := map[string]interface{}{
+				"runtime":                 function.Runtime,
+				"last_update":             aws.ToString(function.LastModified),
+				"handler":                 aws.ToString(function.Handler),
+				"package_type":            function.PackageType,
+				"memory_size_mb":          aws.ToInt32(function.MemorySize),
+				"timeout_seconds":         aws.ToInt32(function.Timeout),
+				"architectures":           function.Architectures,
+				"code_size":               function.CodeSize,
+				"role":                    aws.ToString(function.Role),
+				"layers":                  layers,
+				"aliases":                 getLambdaAliases(svc, name),
+				"versions":                getLambdaVersions(svc, name),
+				"provisioned_concurrency": getProvisionedConcurrency(svc, name),
+				"event_source_mappings":   eventSourceMappings[name],
+			}
+			if function.EphemeralStorage != nil {
+				metadata["ephemeral_storage_mb"] = aws.ToInt32(function.EphemeralStorage.Size)
+			}
+
+			concurrency, err := svc.GetFunctionConcurrency(context.TODO(), &lambda.GetFunctionConcurrencyInput{
+				FunctionName: function.FunctionName,
+			})
+			if err == nil && concurrency.ReservedConcurrentExecutions != nil {
+				metadata["reserved_concurrency"] = *concurrency.ReservedConcurrentExecutions
+			}
+			setCloudWatchRef(metadata, "AWS/Lambda", map[string]string{"FunctionName": name})
+
+			resources = append(resources, ResourceMetadata{
+				ResourceID: name,
+				MetaData:   metadata,
+			})
+		}
+	}
+
+	return resources, nil
+}
+
+func getLambdaAliases(svc *lambda.Client, functionName string) []map[string]interface{} {
+	var aliases []map[string]interface{}
+	paginator := lambda.NewListAliasesPaginator(svc, &lambda.ListAliasesInput{
+		FunctionName: aws.String(functionName),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return aliases
+		}
+		for _, alias := range page.Aliases {
+			metadata := map[string]interface{}{
+				"name":             aws.ToString(alias.Name),
+				"function_version": aws.ToString(alias.FunctionVersion),
+			}
+			// Weighted aliases send part of the traffic to a second version
+			if alias.RoutingConfig != nil && len(alias.RoutingConfig.AdditionalVersionWeights) > 0 {
+				metadata["additional_version_weights"] = alias.RoutingConfig.AdditionalVersionWeights
+			}
+			aliases = append(aliases, metadata)
+		}
+	}
+	return aliases
+}
+
+// getLambdaVersions returns the published versions of a function, without $LATEST
+func getLambdaVersions(svc *lambda.Client, functionName string) []string {
+	var versions []string
+	paginator := lambda.NewListVersionsByFunctionPaginator(svc, &lambda.ListVersionsByFunctionInput{
+		FunctionName: aws.String(functionName),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return versions
+		}
+		for _, version := range page.Versions {
+			if v := aws.ToString(version.Version); v != "$LATEST" {
+				versions = append(versions, v)
+			}
+		}
+	}
+	return versions
+}
+
+func getProvisionedConcurrency(svc *lambda.Client, functionName string) []map[string]interface{} {
+	var configs []map[string]interface{}
+	paginator := lambda.NewListProvisionedConcurrencyConfigsPaginator(svc, &lambda.ListProvisionedConcurrencyConfigsInput{
+		FunctionName: aws.String(functionName),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return configs
+		}
+		for _, config := range page.ProvisionedConcurrencyConfigs {
+			functionArn := aws.ToString(config.FunctionArn)
+			configs = append(configs, map[string]interface{}{
+				"qualifier": functionArn[strings.LastIndex(functionArn, ":")+1:],
+				"requested": aws.ToInt32(config.RequestedProvisionedConcurrentExecutions),
+				"allocated": aws.ToInt32(config.AllocatedProvisionedConcurrentExecutions),
+				"status":    config.Status,
+			})
+		}
+	}
+	return configs
+}
+
+// getEventSourceMappings lists every event source mapping once and groups them by function name
+func getEventSourceMappings(svc *lambda.Client) map[string][]map[string]interface{} {
+	mappings := make(map[string][]map[string]interface{})
+	paginator := lambda.NewListEventSourceMappingsPaginator(svc, &lambda.ListEventSourceMappingsInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			log.Println("Error listing Lambda event source mappings:", err)
+			return mappings
+		}
+		for _, mapping := range page.EventSourceMappings {
+			// arn:aws:lambda:region:account:function:name[:qualifier]
+			parts := strings.Split(aws.ToString(mapping.FunctionArn), ":")
+			if len(parts) < 7 {
+				continue
+			}
+			functionName := parts[6]
+			mappings[functionName] = append(mappings[functionName], map[string]interface{}{
+				"uuid":             aws.ToString(mapping.UUID),
+				"event_source_arn": aws.ToString(mapping.EventSourceArn),
+				"state":            aws.ToString(mapping.State),
+				"batch_size":       aws.ToInt32(mapping.BatchSize),
+			})
+		}
+	}
+	return mappings
+}
@@ -0,0 +1,35 @@
+package entity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindDeprecatedRuntimes(t *testing.T) {
+	table, err := loadLambdaRuntimeTable("")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, table)
+
+	table = []LambdaRuntimeEOL{
+		{Runtime: "python3.8", DeprecationDate: "2024-10-14"},
+		{Runtime: "nodejs20.x", DeprecationDate: "2026-04-30"},
+		{Runtime: "python3.13", DeprecationDate: "2029-06-30"},
+	}
+	functions := []ResourceMetadata{
+		{ResourceID: "old", MetaData: map[string]interface{}{"runtime": "python3.8"}},
+		{ResourceID: "soon", MetaData: map[string]interface{}{"runtime": "nodejs20.x"}},
+		{ResourceID: "current", MetaData: map[string]interface{}{"runtime": "python3.13"}},
+		{ResourceID: "image", MetaData: map[string]interface{}{"runtime": ""}},
+	}
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	items := findDeprecatedRuntimes(functions, table, now, 180)
+	assert.Len(t, items, 2)
+	assert.Equal(t, "old", items[0].FunctionName)
+	assert.Equal(t, "deprecated", items[0].Status)
+	assert.Equal(t, "soon", items[1].FunctionName)
+	assert.Equal(t, "deprecating_soon", items[1].Status)
+	assert.Equal(t, 119, items[1].DaysRemaining)
+}
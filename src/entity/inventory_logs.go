@@ -0,0 +1,303 @@
+package entity
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/ratelimit"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	logsTypes "github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+)
+
+// Log groups grow and receive events between collections
+func init() {
+	addVolatileMetadataKeys("recent_ingestion", "last_event_time", "stored_bytes")
+}
+
+// Number of days without new log events after which a log group is considered idle
+const logIdleDays = 30
+
+// DescribeLogStreams allows five requests per second, so the last event and subscription filters are only looked
+// up for the largest groups
+const maxLogStreamLookups = 1000
+
+// Attempts of a throttled CloudWatch Logs call before it fails
+const logsMaxAttempts = 10
+
+// listLogGroups retrieves CloudWatch Logs log groups with their retention, size, filters and last ingestion
+func listLogGroups(cfg aws.Config) ([]ResourceMetadata, error) {
+	// The describe calls are throttled per account, they are retried with backoff without a retry quota
+	svc := cloudwatchlogs.NewFromConfig(cfg, func(o *cloudwatchlogs.Options) {
+		o.Retryer = retry.NewStandard(func(so *retry.StandardOptions) {
+			so.MaxAttempts = logsMaxAttempts
+			so.RateLimiter = ratelimit.None
+		})
+	})
+
+	metricFilters, filtersErr := getMetricFilters(svc)
+	if filtersErr != nil {
+		log.Println("Error listing CloudWatch Logs metric filters:", filtersErr)
+	}
+
+	var resources []ResourceMetadata
+	paginator := cloudwatchlogs.NewDescribeLogGroupsPaginator(svc, &cloudwatchlogs.DescribeLogGroupsInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return nil, err
+		}
+
+		for _, group := range page.LogGroups {
+			name := aws.ToString(group.LogGroupName)
+			metadata := map[string]interface{}{
+				"resource_type":   "log_group",
+				"name":            name,
+				"log_group_class": group.LogGroupClass,
+				"stored_bytes":    aws.ToInt64(group.StoredBytes),
+				"kms_key_id":      aws.ToString(group.KmsKeyId),
+				// Without a retention policy events never expire
+				"retention_days":     aws.ToInt32(group.RetentionInDays),
+				"infinite_retention": group.RetentionInDays == nil,
+			}
+			if group.CreationTime != nil {
+				metadata["creation_time"] = time.UnixMilli(*group.CreationTime)
+			}
+			if aws.ToInt32(group.MetricFilterCount) > 0 {
+				metadata["metric_filters"] = metricFilters[name]
+			}
+			// A group without stored events has not received any within its retention
+			if aws.ToInt64(group.StoredBytes) == 0 {
+				metadata["recent_ingestion"] = false
+			}
+			setCloudWatchRef(metadata, "AWS/Logs", map[string]string{"LogGroupName": name})
+
+			resources = append(resources, ResourceMetadata{
+				ResourceID: aws.ToString(group.LogGroupArn),
+				MetaData:   metadata,
+			})
+		}
+	}
+
+	lookups := lastEventLookups(resources, maxLogStreamLookups)
+	if len(lookups) == maxLogStreamLookups {
+		log.Printf("Looking up the last event and subscription filters of the %d largest log groups only\n", maxLogStreamLookups)
+	}
+	for _, group := range lookups {
+		name := metadataString(group.MetaData, "name")
+		subscriptionFilters, err := getSubscriptionFilters(svc, name)
+		if err != nil {
+			log.Printf("Error listing subscription filters of log group %s: %v\n", name, err)
+			filtersErr = err
+		} else {
+			group.MetaData["subscription_filters"] = subscriptionFilters
+		}
+
+		lastEvent, ok := getLastEventTime(svc, name)
+		if !ok {
+			continue
+		}
+		group.MetaData["last_event_time"] = lastEvent
+		group.MetaData["recent_ingestion"] = time.Since(lastEvent) < logIdleDays*24*time.Hour
+	}
+
+	if filtersErr != nil {
+		return resources, &partialResourcesError{Step: "log_group_filters",
+			Err: fmt.Errorf("listing CloudWatch Logs filters: %w", filtersErr)}
+	}
+	return resources, nil
+}
+
+// lastEventLookups returns the log groups storing data, largest first, up to limit. Groups left out keep
+// recent_ingestion unset.
+func lastEventLookups(logGroups []ResourceMetadata, limit int) []ResourceMetadata {
+	var lookups []ResourceMetadata
+	for _, group := range logGroups {
+		if metadataInt(group.MetaData, "stored_bytes") > 0 {
+			lookups = append(lookups, group)
+		}
+	}
+	sort.SliceStable(lookups, func(i, j int) bool {
+		return metadataInt(lookups[i].MetaData, "stored_bytes") > metadataInt(lookups[j].MetaData, "stored_bytes")
+	})
+	if len(lookups) > limit {
+		lookups = lookups[:limit]
+	}
+	return lookups
+}
+
+// getMetricFilters returns the metric filters of every log group, keyed by log group name
+func getMetricFilters(svc *cloudwatchlogs.Client) (map[string][]map[string]interface{}, error) {
+	filters := make(map[string][]map[string]interface{})
+	paginator := cloudwatchlogs.NewDescribeMetricFiltersPaginator(svc, &cloudwatchlogs.DescribeMetricFiltersInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return filters, err
+		}
+		for _, filter := range page.MetricFilters {
+			var metrics []string
+			for _, transformation := range filter.MetricTransformations {
+				metrics = append(metrics, aws.ToString(transformation.MetricNamespace)+"/"+aws.ToString(transformation.MetricName))
+			}
+			logGroupName := aws.ToString(filter.LogGroupName)
+			filters[logGroupName] = append(filters[logGroupName], map[string]interface{}{
+				"name":    aws.ToString(filter.FilterName),
+				"pattern": aws.ToString(filter.FilterPattern),
+				"metrics": metrics,
+			})
+		}
+	}
+	return filters, nil
+}
+
+func getSubscriptionFilters(svc *cloudwatchlogs.Client, logGroupName string) ([]map[string]interface{}, error) {
+	var filters []map[string]interface{}
+	paginator := cloudwatchlogs.NewDescribeSubscriptionFiltersPaginator(svc, &cloudwatchlogs.DescribeSubscriptionFiltersInput{
+		LogGroupName: aws.String(logGroupName),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return nil, err
+		}
+		for _, filter := range page.SubscriptionFilters {
+			filters = append(filters, map[string]interface{}{
+				"name":            aws.ToString(filter.FilterName),
+				"pattern":         aws.ToString(filter.FilterPattern),
+				"destination_arn": aws.ToString(filter.DestinationArn),
+			})
+		}
+	}
+	return filters, nil
+}
+
+// getLastEventTime returns the time of the most recent event in any stream of the log group
+func getLastEventTime(svc *cloudwatchlogs.Client, logGroupName string) (time.Time, bool) {
+	result, err := svc.DescribeLogStreams(context.TODO(), &cloudwatchlogs.DescribeLogStreamsInput{
+		LogGroupName: aws.String(logGroupName),
+		OrderBy:      logsTypes.OrderByLastEventTime,
+		Descending:   aws.Bool(true),
+		Limit:        aws.Int32(1),
+	})
+	if err != nil || len(result.LogStreams) == 0 || result.LogStreams[0].LastEventTimestamp == nil {
+		return time.Time{}, false
+	}
+	return time.UnixMilli(*result.LogStreams[0].LastEventTimestamp), true
+}
+
+// attachLogGroups links log groups to the Lambda, API Gateway and ECS resources that write to them,
+// using the names those services give their log groups
+func attachLogGroups(services []ServiceMetadata, logGroups []ResourceMetadata) {
+	owners := make(map[string]ResourceMetadata)
+	ownerServices := make(map[string]string)
+	addOwner := func(serviceName, logGroup string, resource ResourceMetadata) {
+		if _, ok := owners[logGroup]; !ok {
+			owners[logGroup] = resource
+			ownerServices[logGroup] = serviceName
+		}
+	}
+
+	for _, service := range services {
+		code, _ := mapServiceNameToCode(service.ServiceName)
+		for _, resource := range service.Resources {
+			switch code {
+			case "lambda":
+				addOwner(service.ServiceName, "/aws/lambda/"+resource.ResourceID, resource)
+			case "apigateway":
+				// Execution logs are written per stage, the group prefix identifies the API
+				addOwner(service.ServiceName, "API-Gateway-Execution-Logs_"+resource.ResourceID, resource)
+			case "ecs", "fargate":
+				switch metadataString(resource.MetaData, "resource_type") {
+				case "cluster":
+					addOwner(service.ServiceName, "/aws/ecs/containerinsights/"+metadataString(resource.MetaData, "name")+"/performance", resource)
+				case "service":
+					for _, logGroup := range ecsServiceLogGroups(resource.MetaData) {
+						addOwner(service.ServiceName, logGroup, resource)
+					}
+				}
+			}
+		}
+	}
+
+	for _, group := range logGroups {
+		name := metadataString(group.MetaData, "name")
+		key := name
+		if strings.HasPrefix(name, "API-Gateway-Execution-Logs_") {
+			key = strings.SplitN(name, "/", 2)[0]
+		}
+
+		owner, ok := owners[key]
+		if !ok {
+			continue
+		}
+		group.MetaData["owner_resource_id"] = owner.ResourceID
+		group.MetaData["owner_service_name"] = ownerServices[key]
+		logGroupNames, _ := owner.MetaData["log_groups"].([]string)
+		owner.MetaData["log_groups"] = append(logGroupNames, name)
+	}
+}
+
+// ecsServiceLogGroups returns the awslogs groups of a service's containers, or /ecs/<family> by convention
+func ecsServiceLogGroups(metadata map[string]interface{}) []string {
+	var taskDefinition struct {
+		Family     string `json:"family"`
+		Containers []struct {
+			LogGroup string `json:"log_group"`
+		} `json:"containers"`
+	}
+	if err := decodeMetadata(metadata["task_definition"], &taskDefinition); err != nil {
+		return nil
+	}
+
+	var logGroups []string
+	for _, container := range taskDefinition.Containers {
+		if container.LogGroup != "" {
+			logGroups = append(logGroups, container.LogGroup)
+		}
+	}
+	if taskDefinition.Family != "" {
+		logGroups = append(logGroups, "/ecs/"+taskDefinition.Family)
+	}
+	return logGroups
+}
+
+func findLogGroupsWithInfiniteRetention(logGroups []ResourceMetadata) []WasteItem {
+	var items []WasteItem
+	for _, group := range logGroups {
+		// Idle groups are reported by findIdleLogGroups, which skips groups whose last event was not looked up
+		infinite, _ := group.MetaData["infinite_retention"].(bool)
+		recent, looked := group.MetaData["recent_ingestion"].(bool)
+		if infinite && (recent || !looked) {
+			items = append(items, WasteItem{
+				ServiceName: "AmazonCloudWatch",
+				ResourceID:  group.ResourceID,
+				Reason:      "log group has no retention policy and keeps events forever",
+				MetaData:    group.MetaData,
+			})
+		}
+	}
+	return items
+}
+
+func findIdleLogGroups(logGroups []ResourceMetadata) []WasteItem {
+	var items []WasteItem
+	for _, group := range logGroups {
+		recent, looked := group.MetaData["recent_ingestion"].(bool)
+		if !looked || recent || metadataInt(group.MetaData, "stored_bytes") == 0 {
+			continue
+		}
+		items = append(items, WasteItem{
+			ServiceName: "AmazonCloudWatch",
+			ResourceID:  group.ResourceID,
+			Reason:      fmt.Sprintf("log group stores data but received no events in the last %d days", logIdleDays),
+			MetaData:    group.MetaData,
+		})
+	}
+	return items
+}
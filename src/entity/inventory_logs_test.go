@@ -0,0 +1,86 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAttachLogGroups(t *testing.T) {
+	services := []ServiceMetadata{
+		{ServiceName: "AWS Lambda", Resources: []ResourceMetadata{
+			{ResourceID: "handler", MetaData: map[string]interface{}{}},
+		}},
+		{ServiceName: "Amazon API Gateway", Resources: []ResourceMetadata{
+			{ResourceID: "abc123", MetaData: map[string]interface{}{}},
+		}},
+		{ServiceName: "Amazon Elastic Container Service", Resources: []ResourceMetadata{
+			{ResourceID: "arn:service/web", MetaData: map[string]interface{}{
+				"resource_type": "service",
+				"task_definition": map[string]interface{}{
+					"family":     "web",
+					"containers": []map[string]interface{}{{"name": "app", "log_group": "app-logs"}},
+				},
+			}},
+		}},
+	}
+	logGroups := []ResourceMetadata{
+		{ResourceID: "arn:lambda", MetaData: map[string]interface{}{"name": "/aws/lambda/handler"}},
+		{ResourceID: "arn:api", MetaData: map[string]interface{}{"name": "API-Gateway-Execution-Logs_abc123/prod"}},
+		{ResourceID: "arn:ecs", MetaData: map[string]interface{}{"name": "app-logs"}},
+		{ResourceID: "arn:ecs-family", MetaData: map[string]interface{}{"name": "/ecs/web"}},
+		{ResourceID: "arn:other", MetaData: map[string]interface{}{"name": "/custom/logs"}},
+	}
+
+	attachLogGroups(services, logGroups)
+
+	assert.Equal(t, "handler", logGroups[0].MetaData["owner_resource_id"])
+	assert.Equal(t, "abc123", logGroups[1].MetaData["owner_resource_id"])
+	assert.Equal(t, "arn:service/web", logGroups[2].MetaData["owner_resource_id"])
+	assert.Equal(t, "arn:service/web", logGroups[3].MetaData["owner_resource_id"])
+	assert.NotContains(t, logGroups[4].MetaData, "owner_resource_id")
+	assert.Equal(t, []string{"app-logs", "/ecs/web"}, services[2].Resources[0].MetaData["log_groups"])
+}
+
+func TestFindLogGroupWaste(t *testing.T) {
+	logGroups := []ResourceMetadata{
+		{ResourceID: "forever", MetaData: map[string]interface{}{
+			"infinite_retention": true, "recent_ingestion": true, "stored_bytes": int64(10),
+		}},
+		{ResourceID: "idle", MetaData: map[string]interface{}{
+			"infinite_retention": true, "recent_ingestion": false, "stored_bytes": int64(10),
+		}},
+		{ResourceID: "empty", MetaData: map[string]interface{}{
+			"infinite_retention": false, "recent_ingestion": false, "stored_bytes": int64(0),
+		}},
+		// Past the last event lookups, ingestion is unknown
+		{ResourceID: "unknown", MetaData: map[string]interface{}{
+			"infinite_retention": true, "stored_bytes": int64(10),
+		}},
+	}
+
+	items := findLogGroupsWithInfiniteRetention(logGroups)
+	assert.Len(t, items, 2)
+	assert.Equal(t, "forever", items[0].ResourceID)
+	assert.Equal(t, "unknown", items[1].ResourceID)
+
+	items = findIdleLogGroups(logGroups)
+	assert.Len(t, items, 1)
+	assert.Equal(t, "idle", items[0].ResourceID)
+}
+
+func TestLastEventLookups(t *testing.T) {
+	logGroups := []ResourceMetadata{
+		{ResourceID: "small", MetaData: map[string]interface{}{"stored_bytes": int64(10)}},
+		{ResourceID: "empty", MetaData: map[string]interface{}{"stored_bytes": int64(0)}},
+		{ResourceID: "large", MetaData: map[string]interface{}{"stored_bytes": int64(5000)}},
+		{ResourceID: "medium", MetaData: map[string]interface{}{"stored_bytes": int64(200)}},
+	}
+
+	var ids []string
+	for _, group := range lastEventLookups(logGroups, 2) {
+		ids = append(ids, group.ResourceID)
+	}
+	assert.Equal(t, []string{"large", "medium"}, ids)
+	assert.Len(t, lastEventLookups(logGroups, maxLogStreamLookups), 3)
+}
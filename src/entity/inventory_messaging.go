@@ -0,0 +1,301 @@
+package entity
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kinesis"
+	"github.com/aws/aws-sdk-go-v2/service/sfn"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	sqsTypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+// Queue depths change between collections
+func init() {
+	addVolatileMetadataKeys("approximate_messages*")
+}
+
+// listSQSQueues retrieves SQS queues with their depth and dead-letter queue linkage
+func listSQSQueues(cfg aws.Config) ([]ResourceMetadata, error) {
+	svc := sqs.NewFromConfig(cfg)
+
+	var resources []ResourceMetadata
+	paginator := sqs.NewListQueuesPaginator(svc, &sqs.ListQueuesInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return nil, err
+		}
+
+		for _, queueURL := range page.QueueUrls {
+			result, err := svc.GetQueueAttributes(context.TODO(), &sqs.GetQueueAttributesInput{
+				QueueUrl:       aws.String(queueURL),
+				AttributeNames: []sqsTypes.QueueAttributeName{sqsTypes.QueueAttributeNameAll},
+			})
+			if err != nil {
+				log.Printf("Error getting SQS queue attributes: %s\n", queueURL)
+				continue
+			}
+
+			resources = append(resources, ResourceMetadata{
+				ResourceID: result.Attributes["QueueArn"],
+				MetaData:   sqsQueueMetadata(queueURL, result.Attributes),
+			})
+		}
+	}
+
+	linkDeadLetterSources(resources)
+	return resources, nil
+}
+
+// sqsQueueMetadata maps the attributes of a queue to its metadata
+func sqsQueueMetadata(queueURL string, attributes map[string]string) map[string]interface{} {
+	name := queueURL[strings.LastIndex(queueURL, "/")+1:]
+	metadata := map[string]interface{}{
+		"name":                             name,
+		"url":                              queueURL,
+		"fifo":                             attributes["FifoQueue"] == "true",
+		"visibility_timeout":               atoiOrZero(attributes["VisibilityTimeout"]),
+		"message_retention_period":         atoiOrZero(attributes["MessageRetentionPeriod"]),
+		"approximate_messages":             atoiOrZero(attributes["ApproximateNumberOfMessages"]),
+		"approximate_messages_not_visible": atoiOrZero(attributes["ApproximateNumberOfMessagesNotVisible"]),
+		"approximate_messages_delayed":     atoiOrZero(attributes["ApproximateNumberOfMessagesDelayed"]),
+	}
+	if kmsKey, ok := attributes["KmsMasterKeyId"]; ok {
+		metadata["kms_key_id"] = kmsKey
+	}
+
+	// RedrivePolicy is a JSON document, maxReceiveCount may be a string or a number
+	if policy, ok := attributes["RedrivePolicy"]; ok {
+		var redrive struct {
+			DeadLetterTargetArn string      `json:"deadLetterTargetArn"`
+			MaxReceiveCount     interface{} `json:"maxReceiveCount"`
+		}
+		if err := json.Unmarshal([]byte(policy), &redrive); err == nil {
+			metadata["dead_letter_queue_arn"] = redrive.DeadLetterTargetArn
+			metadata["max_receive_count"] = atoiOrZero(fmt.Sprint(redrive.MaxReceiveCount))
+		}
+	}
+	setCloudWatchRef(metadata, "AWS/SQS", map[string]string{"QueueName": name})
+	return metadata
+}
+
+// linkDeadLetterSources records on each dead-letter queue the queues that redrive into it
+func linkDeadLetterSources(queues []ResourceMetadata) {
+	sources := make(map[string][]string)
+	for _, queue := range queues {
+		if dlq := metadataString(queue.MetaData, "dead_letter_queue_arn"); dlq != "" {
+			sources[dlq] = append(sources[dlq], queue.ResourceID)
+		}
+	}
+
+	for _, queue := range queues {
+		if queueSources, ok := sources[queue.ResourceID]; ok {
+			queue.MetaData["dead_letter_source_queues"] = queueSources
+		}
+	}
+}
+
+// listSNSTopics retrieves SNS topics and their subscriptions
+func listSNSTopics(cfg aws.Config) ([]ResourceMetadata, error) {
+	svc := sns.NewFromConfig(cfg)
+
+	var resources []ResourceMetadata
+	paginator := sns.NewListTopicsPaginator(svc, &sns.ListTopicsInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return nil, err
+		}
+
+		for _, topic := range page.Topics {
+			topicArn := aws.ToString(topic.TopicArn)
+			name := topicArn[strings.LastIndex(topicArn, ":")+1:]
+
+			metadata := map[string]interface{}{
+				"name": name,
+			}
+
+			result, err := svc.GetTopicAttributes(context.TODO(), &sns.GetTopicAttributesInput{
+				TopicArn: topic.TopicArn,
+			})
+			if err == nil {
+				attributes := result.Attributes
+				metadata["display_name"] = attributes["DisplayName"]
+				metadata["fifo"] = attributes["FifoTopic"] == "true"
+				metadata["subscriptions_confirmed"] = atoiOrZero(attributes["SubscriptionsConfirmed"])
+				metadata["subscriptions_pending"] = atoiOrZero(attributes["SubscriptionsPending"])
+				if kmsKey, ok := attributes["KmsMasterKeyId"]; ok {
+					metadata["kms_key_id"] = kmsKey
+				}
+			}
+
+			metadata["subscriptions"] = getSNSSubscriptions(svc, topicArn)
+			setCloudWatchRef(metadata, "AWS/SNS", map[string]string{"TopicName": name})
+
+			resources = append(resources, ResourceMetadata{
+				ResourceID: topicArn,
+				MetaData:   metadata,
+			})
+		}
+	}
+
+	return resources, nil
+}
+
+func getSNSSubscriptions(svc *sns.Client, topicArn string) []map[string]interface{} {
+	var subscriptions []map[string]interface{}
+	paginator := sns.NewListSubscriptionsByTopicPaginator(svc, &sns.ListSubscriptionsByTopicInput{
+		TopicArn: aws.String(topicArn),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return subscriptions
+		}
+		for _, subscription := range page.Subscriptions {
+			subscriptions = append(subscriptions, map[string]interface{}{
+				"subscription_arn": aws.ToString(subscription.SubscriptionArn),
+				"protocol":         aws.ToString(subscription.Protocol),
+				"endpoint":         aws.ToString(subscription.Endpoint),
+			})
+		}
+	}
+	return subscriptions
+}
+
+// listKinesisStreams retrieves Kinesis data streams and their shards
+func listKinesisStreams(cfg aws.Config) ([]ResourceMetadata, error) {
+	svc := kinesis.NewFromConfig(cfg)
+
+	var resources []ResourceMetadata
+	paginator := kinesis.NewListStreamsPaginator(svc, &kinesis.ListStreamsInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return nil, err
+		}
+
+		for _, name := range page.StreamNames {
+			desc, err := svc.DescribeStreamSummary(context.TODO(), &kinesis.DescribeStreamSummaryInput{
+				StreamName: aws.String(name),
+			})
+			if err != nil {
+				log.Printf("Error describing Kinesis stream: %s\n", name)
+				continue
+			}
+			stream := desc.StreamDescriptionSummary
+
+			metadata := map[string]interface{}{
+				"name":             name,
+				"status":           stream.StreamStatus,
+				"open_shard_count": aws.ToInt32(stream.OpenShardCount),
+				"retention_hours":  aws.ToInt32(stream.RetentionPeriodHours),
+				"consumer_count":   aws.ToInt32(stream.ConsumerCount),
+				"encryption_type":  stream.EncryptionType,
+				"creation_time":    stream.StreamCreationTimestamp,
+				"shards":           getKinesisShards(svc, name),
+			}
+			if stream.StreamModeDetails != nil {
+				metadata["stream_mode"] = stream.StreamModeDetails.StreamMode
+			}
+			setCloudWatchRef(metadata, "AWS/Kinesis", map[string]string{"StreamName": name})
+
+			resources = append(resources, ResourceMetadata{
+				ResourceID: aws.ToString(stream.StreamARN),
+				MetaData:   metadata,
+			})
+		}
+	}
+
+	return resources, nil
+}
+
+func getKinesisShards(svc *kinesis.Client, streamName string) []map[string]interface{} {
+	var shards []map[string]interface{}
+
+	// StreamName must not be set together with NextToken
+	input := &kinesis.ListShardsInput{StreamName: aws.String(streamName)}
+	for {
+		result, err := svc.ListShards(context.TODO(), input)
+		if err != nil {
+			return shards
+		}
+
+		for _, shard := range result.Shards {
+			metadata := map[string]interface{}{
+				"shard_id": aws.ToString(shard.ShardId),
+				"open":     shard.SequenceNumberRange == nil || shard.SequenceNumberRange.EndingSequenceNumber == nil,
+			}
+			if shard.ParentShardId != nil {
+				metadata["parent_shard_id"] = *shard.ParentShardId
+			}
+			shards = append(shards, metadata)
+		}
+
+		if result.NextToken == nil {
+			break
+		}
+		input = &kinesis.ListShardsInput{NextToken: result.NextToken}
+	}
+	return shards
+}
+
+// listStateMachines retrieves Step Functions state machines
+func listStateMachines(cfg aws.Config) ([]ResourceMetadata, error) {
+	svc := sfn.NewFromConfig(cfg)
+
+	var resources []ResourceMetadata
+	paginator := sfn.NewListStateMachinesPaginator(svc, &sfn.ListStateMachinesInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return nil, err
+		}
+
+		for _, item := range page.StateMachines {
+			arn := aws.ToString(item.StateMachineArn)
+			metadata := map[string]interface{}{
+				"name":          aws.ToString(item.Name),
+				"type":          item.Type,
+				"creation_date": item.CreationDate,
+			}
+
+			desc, err := svc.DescribeStateMachine(context.TODO(), &sfn.DescribeStateMachineInput{
+				StateMachineArn: item.StateMachineArn,
+			})
+			if err == nil {
+				metadata["status"] = desc.Status
+				metadata["role_arn"] = aws.ToString(desc.RoleArn)
+				if desc.LoggingConfiguration != nil {
+					metadata["logging_level"] = desc.LoggingConfiguration.Level
+				}
+				if desc.TracingConfiguration != nil {
+					metadata["tracing_enabled"] = desc.TracingConfiguration.Enabled
+				}
+			}
+			setCloudWatchRef(metadata, "AWS/States", map[string]string{"StateMachineArn": arn})
+
+			resources = append(resources, ResourceMetadata{
+				ResourceID: arn,
+				MetaData:   metadata,
+			})
+		}
+	}
+
+	return resources, nil
+}
+
+func atoiOrZero(value string) int {
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0
+	}
+	return n
+}
@@ -0,0 +1,44 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSQSQueueMetadata(t *testing.T) {
+	orders := sqsQueueMetadata("https://sqs.eu-west-1.amazonaws.com/123/orders", map[string]string{
+		"QueueArn":                    "arn:aws:sqs:eu-west-1:123:orders",
+		"VisibilityTimeout":           "30",
+		"ApproximateNumberOfMessages": "12",
+		"RedrivePolicy":               `{"deadLetterTargetArn":"arn:aws:sqs:eu-west-1:123:orders-dlq","maxReceiveCount":5}`,
+	})
+	assert.Equal(t, "orders", orders["name"])
+	assert.Equal(t, 30, orders["visibility_timeout"])
+	assert.Equal(t, 12, orders["approximate_messages"])
+	assert.Equal(t, "arn:aws:sqs:eu-west-1:123:orders-dlq", orders["dead_letter_queue_arn"])
+	assert.Equal(t, 5, orders["max_receive_count"])
+
+	// Older queues return maxReceiveCount as a string
+	invoices := sqsQueueMetadata("https://sqs.eu-west-1.amazonaws.com/123/invoices", map[string]string{
+		"RedrivePolicy": `{"deadLetterTargetArn":"arn:aws:sqs:eu-west-1:123:orders-dlq","maxReceiveCount":"3"}`,
+	})
+	assert.Equal(t, 3, invoices["max_receive_count"])
+
+	dlq := sqsQueueMetadata("https://sqs.eu-west-1.amazonaws.com/123/orders-dlq", map[string]string{
+		"QueueArn":          "arn:aws:sqs:eu-west-1:123:orders-dlq",
+		"VisibilityTimeout": "not a number",
+	})
+	assert.Equal(t, 0, dlq["visibility_timeout"])
+	assert.NotContains(t, dlq, "dead_letter_queue_arn")
+
+	queues := []ResourceMetadata{
+		{ResourceID: "arn:aws:sqs:eu-west-1:123:orders", MetaData: orders},
+		{ResourceID: "arn:aws:sqs:eu-west-1:123:invoices", MetaData: invoices},
+		{ResourceID: "arn:aws:sqs:eu-west-1:123:orders-dlq", MetaData: dlq},
+	}
+	linkDeadLetterSources(queues)
+
+	assert.Equal(t, []string{"arn:aws:sqs:eu-west-1:123:orders", "arn:aws:sqs:eu-west-1:123:invoices"}, dlq["dead_letter_source_queues"])
+	assert.NotContains(t, orders, "dead_letter_source_queues")
+}
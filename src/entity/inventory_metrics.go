@@ -0,0 +1,338 @@
+package entity
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	"github.com/spf13/cobra"
+)
+
+// The metric catalog grows and shrinks as CloudWatch publishes and expires metrics
+func init() {
+	addVolatileMetadataKeys("metric_count", "dimension_set_count", "metrics")
+}
+
+// MetricCatalogEntry is a CloudWatch metric with every dimension set it is published with
+type MetricCatalogEntry struct {
+	MetricName        string               `json:"metric_name"`
+	DimensionSetCount int                  `json:"dimension_set_count"`
+	DimensionSets     []MetricDimensionSet `json:"dimension_sets"`
+}
+
+// MetricDimensionSet is one combination of dimensions, linked to the inventory resource it describes
+type MetricDimensionSet struct {
+	Dimensions  map[string]string `json:"dimensions"`
+	ResourceID  string            `json:"resource_id,omitempty"`
+	ServiceName string            `json:"service_name,omitempty"`
+}
+
+func metricsInventoryCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "metrics",
+		Short: "Browse the CloudWatch metric catalog of an inventory file",
+		Run: func(cmd *cobra.Command, args []string) {
+			in, _ := cmd.Flags().GetString("in")
+			namespace, _ := cmd.Flags().GetString("namespace")
+			metric, _ := cmd.Flags().GetString("metric")
+			err := browseMetricCatalog(in, namespace, metric, awsConfigOptionsFromFlags(cmd))
+			if err != nil {
+				log.Printf("Error browsing metric catalog: %v\n", err)
+			}
+		},
+	}
+	cmd.Flags().String("in", "", "Input path of the inventory file")
+	cmd.Flags().String("namespace", "", "Only show metrics in this namespace")
+	cmd.Flags().String("metric", "", "Only show metrics with this name")
+	addAWSConfigFlags(cmd)
+
+	cmd.MarkFlagRequired("in")
+	cmd.MarkFlagFilename("in")
+	return cmd
+}
+
+// readInventoryFile loads an inventory written by inventory make
+func readInventoryFile(in string) ([]ServiceMetadata, error) {
+	data, err := os.ReadFile(in)
+	if err != nil {
+		return nil, err
+	}
+
+	var services []ServiceMetadata
+	if err := json.Unmarshal(data, &services); err != nil {
+		return nil, err
+	}
+	return services, nil
+}
+
+func browseMetricCatalog(in, namespace, metric string, awsOptions awsConfigOptions) error {
+	services, err := readInventoryFile(in)
+	if err != nil {
+		return err
+	}
+
+	// The catalog is only collected when CloudWatch is a top service, otherwise read it live
+	catalog := findMetricCatalog(services)
+	if catalog == nil {
+		log.Println("No metric catalog in inventory, listing CloudWatch metrics")
+		cfg, err := loadAWSConfig(awsOptions)
+		if err != nil {
+			return err
+		}
+		catalog, err = listCloudWatchMetrics(cfg)
+		if err != nil {
+			return err
+		}
+		linkMetricCatalog(catalog, services)
+	}
+
+	for _, ns := range catalog {
+		if namespace != "" && ns.ResourceID != namespace {
+			continue
+		}
+
+		var entries []MetricCatalogEntry
+		if err := decodeMetadata(ns.MetaData["metrics"], &entries); err != nil {
+			return err
+		}
+
+		printed := false
+		for _, entry := range entries {
+			if metric != "" && entry.MetricName != metric {
+				continue
+			}
+			if !printed {
+				fmt.Printf("%s (%d metrics, %d dimension sets)\n", ns.ResourceID,
+					metadataInt(ns.MetaData, "metric_count"), metadataInt(ns.MetaData, "dimension_set_count"))
+				printed = true
+			}
+
+			fmt.Printf("  %s (%d)\n", entry.MetricName, entry.DimensionSetCount)
+			for _, set := range entry.DimensionSets {
+				line := "    " + formatDimensions(set.Dimensions)
+				if set.ResourceID != "" {
+					line += fmt.Sprintf(" -> %s (%s)", set.ResourceID, set.ServiceName)
+				}
+				fmt.Println(line)
+			}
+		}
+	}
+	return nil
+}
+
+// listCloudWatchMetrics builds a catalog of namespace -> metric -> dimension sets, one resource per namespace
+func listCloudWatchMetrics(cfg aws.Config) ([]ResourceMetadata, error) {
+	svc := cloudwatch.NewFromConfig(cfg)
+
+	catalog := make(map[string]map[string][]map[string]string)
+	paginator := cloudwatch.NewListMetricsPaginator(svc, &cloudwatch.ListMetricsInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return nil, err
+		}
+
+		for _, metric := range page.Metrics {
+			dimensions := make(map[string]string)
+			for _, dimension := range metric.Dimensions {
+				dimensions[aws.ToString(dimension.Name)] = aws.ToString(dimension.Value)
+			}
+
+			namespace := aws.ToString(metric.Namespace)
+			if catalog[namespace] == nil {
+				catalog[namespace] = make(map[string][]map[string]string)
+			}
+			name := aws.ToString(metric.MetricName)
+			catalog[namespace][name] = append(catalog[namespace][name], dimensions)
+		}
+	}
+
+	return buildMetricCatalog(catalog), nil
+}
+
+func buildMetricCatalog(catalog map[string]map[string][]map[string]string) []ResourceMetadata {
+	var resources []ResourceMetadata
+	for _, namespace := range sortedKeys(catalog) {
+		metrics := catalog[namespace]
+
+		var entries []MetricCatalogEntry
+		dimensionSetCount := 0
+		for _, name := range sortedKeys(metrics) {
+			sets := metrics[name]
+			sort.Slice(sets, func(i, j int) bool {
+				return formatDimensions(sets[i]) < formatDimensions(sets[j])
+			})
+
+			entry := MetricCatalogEntry{MetricName: name, DimensionSetCount: len(sets)}
+			for _, dimensions := range sets {
+				entry.DimensionSets = append(entry.DimensionSets, MetricDimensionSet{Dimensions: dimensions})
+			}
+			entries = append(entries, entry)
+			dimensionSetCount += len(sets)
+		}
+
+		resources = append(resources, ResourceMetadata{
+			ResourceID: namespace,
+			MetaData: map[string]interface{}{
+				"resource_type":       "metric_namespace",
+				"metric_count":        len(entries),
+				"dimension_set_count": dimensionSetCount,
+				"metrics":             entries,
+			},
+		})
+	}
+	return resources
+}
+
+// cloudWatchResource is an inventory resource with the CloudWatch dimensions its metrics are published with
+type cloudWatchResource struct {
+	serviceName string
+	resource    ResourceMetadata
+	dimensions  map[string]string
+}
+
+// cloudWatchIndex looks up inventory resources by CloudWatch namespace and dimensions
+type cloudWatchIndex map[string][]cloudWatchResource
+
+func newCloudWatchIndex(services []ServiceMetadata) cloudWatchIndex {
+	index := make(cloudWatchIndex)
+	for _, service := range services {
+		for _, resource := range service.Resources {
+			namespace := metadataString(resource.MetaData, "cloudwatch_namespace")
+			dimensions := metadataStringMap(resource.MetaData, "cloudwatch_dimensions")
+			if namespace == "" || len(dimensions) == 0 {
+				continue
+			}
+			index[namespace] = append(index[namespace], cloudWatchResource{service.ServiceName, resource, dimensions})
+		}
+	}
+	return index
+}
+
+// match returns the resource whose CloudWatch dimensions are all contained in the given dimensions.
+// When several resources match, the most specific one, with the most dimensions, wins.
+func (index cloudWatchIndex) match(namespace string, dimensions map[string]string) (cloudWatchResource, bool) {
+	var best cloudWatchResource
+	found := false
+	for _, candidate := range index[namespace] {
+		if len(candidate.dimensions) > len(best.dimensions) && containsDimensions(dimensions, candidate.dimensions) {
+			best = candidate
+			found = true
+		}
+	}
+	return best, found
+}
+
+// targetsKnownKind reports whether the dimensions name the same kind of resource as one in the inventory,
+// e.g. an InstanceId in AWS/EC2, whatever its value
+func (index cloudWatchIndex) targetsKnownKind(namespace string, dimensions map[string]string) bool {
+	for _, candidate := range index[namespace] {
+		kind := true
+		for name := range candidate.dimensions {
+			if _, ok := dimensions[name]; !ok {
+				kind = false
+				break
+			}
+		}
+		if kind {
+			return true
+		}
+	}
+	return false
+}
+
+// linkMetricCatalog links every dimension set to the inventory resource whose CloudWatch dimensions it contains
+func linkMetricCatalog(catalog []ResourceMetadata, services []ServiceMetadata) {
+	index := newCloudWatchIndex(services)
+	for _, ns := range catalog {
+		entries, ok := ns.MetaData["metrics"].([]MetricCatalogEntry)
+		if !ok {
+			continue
+		}
+		for _, entry := range entries {
+			for i := range entry.DimensionSets {
+				set := &entry.DimensionSets[i]
+				if match, ok := index.match(ns.ResourceID, set.Dimensions); ok {
+					set.ResourceID = match.resource.ResourceID
+					set.ServiceName = match.serviceName
+				}
+			}
+		}
+	}
+}
+
+// findMetricCatalog returns the catalog collected for the CloudWatch service, if any
+func findMetricCatalog(services []ServiceMetadata) []ResourceMetadata {
+	var catalog []ResourceMetadata
+	for _, service := range services {
+		if code, _ := mapServiceNameToCode(service.ServiceName); code != "cloudwatch" {
+			continue
+		}
+		// Log groups and alarms are kept next to the catalog
+		for _, resource := range service.Resources {
+			if metadataString(resource.MetaData, "resource_type") == "metric_namespace" {
+				catalog = append(catalog, resource)
+			}
+		}
+	}
+	return catalog
+}
+
+func containsDimensions(dimensions, subset map[string]string) bool {
+	for name, value := range subset {
+		if dimensions[name] != value {
+			return false
+		}
+	}
+	return true
+}
+
+func formatDimensions(dimensions map[string]string) string {
+	if len(dimensions) == 0 {
+		return "(no dimensions)"
+	}
+	var pairs []string
+	for _, name := range sortedKeys(dimensions) {
+		pairs = append(pairs, name+"="+dimensions[name])
+	}
+	return strings.Join(pairs, ", ")
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// metadataStringMap reads a string map that was set directly or loaded from an inventory file
+func metadataStringMap(metadata map[string]interface{}, key string) map[string]string {
+	switch value := metadata[key].(type) {
+	case map[string]string:
+		return value
+	case map[string]interface{}:
+		result := make(map[string]string)
+		for k, v := range value {
+			result[k] = fmt.Sprint(v)
+		}
+		return result
+	}
+	return nil
+}
+
+// decodeMetadata converts a metadata value loaded from an inventory file into a typed value
+func decodeMetadata(value interface{}, target interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, target)
+}
@@ -0,0 +1,49 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLinkMetricCatalog(t *testing.T) {
+	catalog := buildMetricCatalog(map[string]map[string][]map[string]string{
+		"AWS/EC2": {
+			"CPUUtilization": {{"InstanceId": "i-2"}, {"InstanceId": "i-1"}, {}},
+		},
+		"AWS/SageMaker": {
+			"Invocations": {{"EndpointName": "ep", "VariantName": "AllTraffic"}},
+		},
+	})
+
+	services := []ServiceMetadata{
+		{ServiceName: "Amazon Elastic Compute Cloud - Compute", Resources: []ResourceMetadata{
+			{ResourceID: "i-1", MetaData: map[string]interface{}{
+				"cloudwatch_namespace":  "AWS/EC2",
+				"cloudwatch_dimensions": map[string]string{"InstanceId": "i-1"},
+			}},
+		}},
+		{ServiceName: "Amazon SageMaker", Resources: []ResourceMetadata{
+			// Loaded from an inventory file
+			{ResourceID: "arn:endpoint/ep", MetaData: map[string]interface{}{
+				"cloudwatch_namespace":  "AWS/SageMaker",
+				"cloudwatch_dimensions": map[string]interface{}{"EndpointName": "ep"},
+			}},
+		}},
+	}
+	linkMetricCatalog(catalog, services)
+
+	assert.Len(t, catalog, 2)
+	assert.Equal(t, "AWS/EC2", catalog[0].ResourceID)
+	assert.Equal(t, 3, catalog[0].MetaData["dimension_set_count"])
+
+	ec2 := catalog[0].MetaData["metrics"].([]MetricCatalogEntry)
+	assert.Equal(t, 3, ec2[0].DimensionSetCount)
+	assert.Empty(t, ec2[0].DimensionSets[0].ResourceID)
+	assert.Equal(t, "i-1", ec2[0].DimensionSets[1].ResourceID)
+	assert.Empty(t, ec2[0].DimensionSets[2].ResourceID)
+
+	sagemaker := catalog[1].MetaData["metrics"].([]MetricCatalogEntry)
+	assert.Equal(t, "arn:endpoint/ep", sagemaker[0].DimensionSets[0].ResourceID)
+	assert.Equal(t, "Amazon SageMaker", sagemaker[0].DimensionSets[0].ServiceName)
+}
@@ -0,0 +1,219 @@
+package entity
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/bedrock"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	"github.com/aws/aws-sdk-go-v2/service/sagemaker"
+	smTypes "github.com/aws/aws-sdk-go-v2/service/sagemaker/types"
+)
+
+// listSageMakerEndpoints retrieves SageMaker endpoints with their endpoint config and instance types
+func listSageMakerEndpoints(cfg aws.Config) ([]ResourceMetadata, error) {
+	svc := sagemaker.NewFromConfig(cfg)
+
+	var resources []ResourceMetadata
+	paginator := sagemaker.NewListEndpointsPaginator(svc, &sagemaker.ListEndpointsInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return nil, err
+		}
+
+		for _, summary := range page.Endpoints {
+			name := aws.ToString(summary.EndpointName)
+			metadata := map[string]interface{}{
+				"name":          name,
+				"status":        summary.EndpointStatus,
+				"creation_time": summary.CreationTime,
+				"last_modified": summary.LastModifiedTime,
+			}
+
+			desc, err := svc.DescribeEndpoint(context.TODO(), &sagemaker.DescribeEndpointInput{
+				EndpointName: summary.EndpointName,
+			})
+			if err != nil {
+				log.Printf("Error describing SageMaker endpoint: %s\n", name)
+			} else {
+				configName := aws.ToString(desc.EndpointConfigName)
+				metadata["endpoint_config_name"] = configName
+				metadata["variants"] = getSageMakerVariants(svc, name, configName, desc.ProductionVariants)
+			}
+			setCloudWatchRef(metadata, "AWS/SageMaker", map[string]string{"EndpointName": name})
+
+			resources = append(resources, ResourceMetadata{
+				ResourceID: aws.ToString(summary.EndpointArn),
+				MetaData:   metadata,
+			})
+		}
+	}
+
+	return resources, nil
+}
+
+// getSageMakerVariants merges the configured production variants with their current state
+func getSageMakerVariants(svc *sagemaker.Client, endpointName, configName string, current []smTypes.ProductionVariantSummary) []map[string]interface{} {
+	var configured []smTypes.ProductionVariant
+	if configName != "" {
+		config, err := svc.DescribeEndpointConfig(context.TODO(), &sagemaker.DescribeEndpointConfigInput{
+			EndpointConfigName: aws.String(configName),
+		})
+		if err == nil {
+			configured = config.ProductionVariants
+		}
+	}
+	return sageMakerVariantMetadata(endpointName, current, configured)
+}
+
+// sageMakerVariantMetadata maps the current variants of an endpoint, with the model and instance type of the
+// variant of the same name in the endpoint config
+func sageMakerVariantMetadata(endpointName string, current []smTypes.ProductionVariantSummary, config []smTypes.ProductionVariant) []map[string]interface{} {
+	configured := make(map[string]smTypes.ProductionVariant)
+	for _, variant := range config {
+		configured[aws.ToString(variant.VariantName)] = variant
+	}
+
+	var variants []map[string]interface{}
+	for _, variant := range current {
+		name := aws.ToString(variant.VariantName)
+		metadata := map[string]interface{}{
+			"variant_name":           name,
+			"current_instance_count": aws.ToInt32(variant.CurrentInstanceCount),
+			"desired_instance_count": aws.ToInt32(variant.DesiredInstanceCount),
+			"current_weight":         aws.ToFloat32(variant.CurrentWeight),
+			"cloudwatch_dimensions": map[string]string{
+				"EndpointName": endpointName,
+				"VariantName":  name,
+			},
+		}
+
+		if config, ok := configured[name]; ok {
+			metadata["model_name"] = aws.ToString(config.ModelName)
+			metadata["instance_type"] = config.InstanceType
+			metadata["initial_instance_count"] = aws.ToInt32(config.InitialInstanceCount)
+			if config.ServerlessConfig != nil {
+				metadata["serverless_memory_mb"] = aws.ToInt32(config.ServerlessConfig.MemorySizeInMB)
+				metadata["serverless_max_concurrency"] = aws.ToInt32(config.ServerlessConfig.MaxConcurrency)
+			}
+		}
+
+		variants = append(variants, metadata)
+	}
+	return variants
+}
+
+// listBedrockResources retrieves provisioned throughput, the models invoked on demand
+// and the account's model invocation logging configuration
+func listBedrockResources(cfg aws.Config) ([]ResourceMetadata, error) {
+	svc := bedrock.NewFromConfig(cfg)
+
+	var resources []ResourceMetadata
+	paginator := bedrock.NewListProvisionedModelThroughputsPaginator(svc, &bedrock.ListProvisionedModelThroughputsInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return nil, err
+		}
+
+		for _, provisioned := range page.ProvisionedModelSummaries {
+			arn := aws.ToString(provisioned.ProvisionedModelArn)
+			metadata := map[string]interface{}{
+				"resource_type":        "provisioned_throughput",
+				"name":                 aws.ToString(provisioned.ProvisionedModelName),
+				"model_arn":            aws.ToString(provisioned.ModelArn),
+				"foundation_model_arn": aws.ToString(provisioned.FoundationModelArn),
+				"model_units":          aws.ToInt32(provisioned.ModelUnits),
+				"desired_model_units":  aws.ToInt32(provisioned.DesiredModelUnits),
+				"status":               provisioned.Status,
+				"commitment_duration":  provisioned.CommitmentDuration,
+			}
+			if provisioned.CommitmentExpirationTime != nil {
+				metadata["commitment_expiration_time"] = provisioned.CommitmentExpirationTime
+			}
+			setCloudWatchRef(metadata, "AWS/Bedrock", map[string]string{"ModelId": arn})
+
+			resources = append(resources, ResourceMetadata{
+				ResourceID: arn,
+				MetaData:   metadata,
+			})
+		}
+	}
+
+	// The models found before listing their metrics failed are kept
+	invokedModels, invokedErr := getBedrockInvokedModels(cfg)
+	resources = append(resources, invokedModels...)
+	if invokedErr != nil {
+		invokedErr = &partialResourcesError{Step: "bedrock_invoked_models", Err: fmt.Errorf("listing Bedrock invocation metrics: %w", invokedErr)}
+	}
+
+	logging, err := svc.GetModelInvocationLoggingConfiguration(context.TODO(), &bedrock.GetModelInvocationLoggingConfigurationInput{})
+	if err != nil {
+		if invokedErr != nil {
+			log.Println("Error", invokedErr)
+		}
+		return resources, &partialResourcesError{Step: "bedrock_invocation_logging",
+			Err: fmt.Errorf("getting Bedrock model invocation logging configuration: %w", err)}
+	}
+
+	metadata := map[string]interface{}{
+		"resource_type": "invocation_logging",
+		"enabled":       logging.LoggingConfig != nil,
+	}
+	if config := logging.LoggingConfig; config != nil {
+		metadata["text_data_delivery"] = aws.ToBool(config.TextDataDeliveryEnabled)
+		metadata["image_data_delivery"] = aws.ToBool(config.ImageDataDeliveryEnabled)
+		metadata["embedding_data_delivery"] = aws.ToBool(config.EmbeddingDataDeliveryEnabled)
+		if config.CloudWatchConfig != nil {
+			metadata["log_group_name"] = aws.ToString(config.CloudWatchConfig.LogGroupName)
+		}
+		if config.S3Config != nil {
+			metadata["s3_bucket"] = aws.ToString(config.S3Config.BucketName)
+		}
+	}
+	resources = append(resources, ResourceMetadata{
+		ResourceID: "model-invocation-logging",
+		MetaData:   metadata,
+	})
+
+	return resources, invokedErr
+}
+
+// getBedrockInvokedModels lists the models with on-demand invocation metrics in the last two weeks
+func getBedrockInvokedModels(cfg aws.Config) ([]ResourceMetadata, error) {
+	svc := cloudwatch.NewFromConfig(cfg)
+
+	var resources []ResourceMetadata
+	paginator := cloudwatch.NewListMetricsPaginator(svc, &cloudwatch.ListMetricsInput{
+		Namespace:  aws.String("AWS/Bedrock"),
+		MetricName: aws.String("Invocations"),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return resources, err
+		}
+
+		for _, metric := range page.Metrics {
+			// Per model totals are published with ModelId as the only dimension
+			if len(metric.Dimensions) != 1 || aws.ToString(metric.Dimensions[0].Name) != "ModelId" {
+				continue
+			}
+			modelID := aws.ToString(metric.Dimensions[0].Value)
+			metadata := map[string]interface{}{
+				"resource_type": "model",
+				"model_id":      modelID,
+			}
+			setCloudWatchRef(metadata, "AWS/Bedrock", map[string]string{"ModelId": modelID})
+
+			resources = append(resources, ResourceMetadata{
+				ResourceID: modelID,
+				MetaData:   metadata,
+			})
+		}
+	}
+	return resources, nil
+}
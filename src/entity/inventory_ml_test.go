@@ -0,0 +1,63 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	smTypes "github.com/aws/aws-sdk-go-v2/service/sagemaker/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSageMakerVariantMetadata(t *testing.T) {
+	current := []smTypes.ProductionVariantSummary{
+		{
+			VariantName:          aws.String("primary"),
+			CurrentInstanceCount: aws.Int32(2),
+			DesiredInstanceCount: aws.Int32(3),
+			CurrentWeight:        aws.Float32(0.9),
+		},
+		{VariantName: aws.String("serverless"), CurrentWeight: aws.Float32(0.1)},
+		{VariantName: aws.String("shadow")},
+	}
+	config := []smTypes.ProductionVariant{
+		{
+			VariantName:          aws.String("primary"),
+			ModelName:            aws.String("ranker-v2"),
+			InstanceType:         smTypes.ProductionVariantInstanceTypeMlG5Xlarge,
+			InitialInstanceCount: aws.Int32(2),
+		},
+		{
+			VariantName: aws.String("serverless"),
+			ModelName:   aws.String("ranker-v1"),
+			ServerlessConfig: &smTypes.ProductionVariantServerlessConfig{
+				MemorySizeInMB: aws.Int32(2048),
+				MaxConcurrency: aws.Int32(10),
+			},
+		},
+	}
+
+	variants := sageMakerVariantMetadata("ranker", current, config)
+	assert.Len(t, variants, 3)
+
+	primary := variants[0]
+	assert.Equal(t, "primary", primary["variant_name"])
+	assert.Equal(t, int32(2), primary["current_instance_count"])
+	assert.Equal(t, int32(3), primary["desired_instance_count"])
+	assert.Equal(t, float32(0.9), primary["current_weight"])
+	assert.Equal(t, "ranker-v2", primary["model_name"])
+	assert.Equal(t, smTypes.ProductionVariantInstanceTypeMlG5Xlarge, primary["instance_type"])
+	assert.Equal(t, int32(2), primary["initial_instance_count"])
+	assert.Equal(t, map[string]string{"EndpointName": "ranker", "VariantName": "primary"}, primary["cloudwatch_dimensions"])
+	assert.NotContains(t, primary, "serverless_memory_mb")
+
+	serverless := variants[1]
+	assert.Equal(t, "ranker-v1", serverless["model_name"])
+	assert.Equal(t, int32(2048), serverless["serverless_memory_mb"])
+	assert.Equal(t, int32(10), serverless["serverless_max_concurrency"])
+
+	// A variant missing from the endpoint config keeps its current state only
+	shadow := variants[2]
+	assert.Equal(t, "shadow", shadow["variant_name"])
+	assert.NotContains(t, shadow, "model_name")
+	assert.NotContains(t, shadow, "instance_type")
+}
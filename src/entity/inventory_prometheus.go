@@ -0,0 +1,125 @@
+package entity
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Targets report the state of their last scrape
+func init() {
+	addVolatileMetadataKeys("health", "last_error", "last_scrape", "last_scrape_duration")
+}
+
+// prometheusResponse is the envelope of every Prometheus HTTP API response
+type prometheusResponse struct {
+	Status    string          `json:"status"`
+	Data      json.RawMessage `json:"data"`
+	ErrorType string          `json:"errorType"`
+	Error     string          `json:"error"`
+}
+
+type prometheusTarget struct {
+	Labels             map[string]string `json:"labels"`
+	ScrapePool         string            `json:"scrapePool"`
+	ScrapeURL          string            `json:"scrapeUrl"`
+	LastError          string            `json:"lastError"`
+	LastScrape         string            `json:"lastScrape"`
+	LastScrapeDuration float64           `json:"lastScrapeDuration"`
+	Health             string            `json:"health"`
+	ScrapeInterval     string            `json:"scrapeInterval"`
+}
+
+// listPrometheusJobs reads the active scrape targets of a Prometheus server and returns one service per job,
+// with its targets as resources and the metric names the job exposes
+func listPrometheusJobs(address string) ([]ServiceMetadata, error) {
+	address = strings.TrimSuffix(address, "/")
+
+	var targets struct {
+		ActiveTargets []prometheusTarget `json:"activeTargets"`
+	}
+	if err := getPrometheusAPI(address, "/api/v1/targets", url.Values{"state": {"active"}}, &targets); err != nil {
+		return nil, err
+	}
+
+	jobs := make(map[string][]ResourceMetadata)
+	for _, target := range targets.ActiveTargets {
+		job := target.Labels["job"]
+		if job == "" {
+			job = target.ScrapePool
+		}
+		id := target.Labels["instance"]
+		if id == "" {
+			id = target.ScrapeURL
+		}
+
+		jobs[job] = append(jobs[job], ResourceMetadata{
+			ResourceID: id,
+			MetaData: map[string]interface{}{
+				"resource_type":        "target",
+				"labels":               target.Labels,
+				"scrape_url":           target.ScrapeURL,
+				"health":               target.Health,
+				"last_error":           target.LastError,
+				"last_scrape":          target.LastScrape,
+				"last_scrape_duration": target.LastScrapeDuration,
+				"scrape_interval":      target.ScrapeInterval,
+			},
+		})
+	}
+
+	var services []ServiceMetadata
+	for _, job := range sortedKeys(jobs) {
+		resources := jobs[job]
+		sort.SliceStable(resources, func(i, j int) bool {
+			return resources[i].ResourceID < resources[j].ResourceID
+		})
+
+		// Metric names are matched by the job label, the same selector PQL queries use
+		var metricNames []string
+		selector := fmt.Sprintf("{job=%q}", job)
+		if err := getPrometheusAPI(address, "/api/v1/label/__name__/values", url.Values{"match[]": {selector}}, &metricNames); err != nil {
+			return nil, err
+		}
+
+		services = append(services, ServiceMetadata{
+			ServiceName: job,
+			Resources:   resources,
+			MetaData: map[string]interface{}{
+				"source":       "prometheus",
+				"prom_address": address,
+				"metric_count": len(metricNames),
+				"metric_names": metricNames,
+			},
+		})
+	}
+	return services, nil
+}
+
+// getPrometheusAPI calls a Prometheus HTTP API endpoint and decodes the data of a successful response
+func getPrometheusAPI(address, path string, query url.Values, target interface{}) error {
+	req, err := http.NewRequest("GET", address+path+"?"+query.Encode(), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Errors are reported in the envelope with a 4xx or 5xx status
+	var response prometheusResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return fmt.Errorf("failed to decode response with HTTP status %s: %w", resp.Status, err)
+	}
+	if response.Status != "success" {
+		return fmt.Errorf("prometheus %s: %s", response.ErrorType, response.Error)
+	}
+	return json.Unmarshal(response.Data, target)
+}
@@ -0,0 +1,45 @@
+package entity
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListPrometheusJobs(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/targets":
+			w.Write([]byte(`{"status":"success","data":{"activeTargets":[
+				{"labels":{"instance":"node-b:9100","job":"node"},"scrapeUrl":"http://node-b:9100/metrics","health":"down","lastError":"connection refused"},
+				{"labels":{"instance":"node-a:9100","job":"node"},"scrapeUrl":"http://node-a:9100/metrics","health":"up"},
+				{"labels":{"instance":"localhost:9090","job":"prometheus"},"scrapeUrl":"http://localhost:9090/metrics","health":"up"}
+			]}}`))
+		case "/api/v1/label/__name__/values":
+			if r.URL.Query().Get("match[]") == `{job="node"}` {
+				w.Write([]byte(`{"status":"success","data":["node_cpu_seconds_total","up"]}`))
+				return
+			}
+			w.Write([]byte(`{"status":"success","data":["prometheus_build_info","up"]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"status":"error","errorType":"not_found","error":"unknown path"}`))
+		}
+	}))
+	defer server.Close()
+
+	services, err := listPrometheusJobs(server.URL + "/")
+
+	assert.NoError(t, err)
+	assert.Len(t, services, 2)
+	assert.Equal(t, "node", services[0].ServiceName)
+	assert.Equal(t, "node-a:9100", services[0].Resources[0].ResourceID)
+	assert.Equal(t, "connection refused", services[0].Resources[1].MetaData["last_error"])
+	assert.Equal(t, []string{"node_cpu_seconds_total", "up"}, services[0].MetaData["metric_names"])
+	assert.Equal(t, "prometheus", services[1].ServiceName)
+
+	err = getPrometheusAPI(server.URL, "/api/v1/unknown", nil, &services)
+	assert.EqualError(t, err, "prometheus not_found: unknown path")
+}
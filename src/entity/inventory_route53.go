@@ -0,0 +1,371 @@
+package entity
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/route53"
+	r53Types "github.com/aws/aws-sdk-go-v2/service/route53/types"
+	"github.com/spf13/cobra"
+)
+
+// Health checkers report a status from each location on every collection
+func init() {
+	addVolatileMetadataKeys("healthy_checkers")
+}
+
+// Route 53 considers an endpoint healthy when more than this share of its checkers report success
+const healthyCheckerPercent = 18
+
+// DanglingRecord is a CNAME or alias record pointing at an AWS resource that is not in the inventory
+type DanglingRecord struct {
+	ZoneID        string `json:"zone_id"`
+	ZoneName      string `json:"zone_name"`
+	Name          string `json:"name"`
+	Type          string `json:"type"`
+	Target        string `json:"target"`
+	TargetService string `json:"target_service"`
+}
+
+func danglingRecordsInventoryCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "dangling-records",
+		Short: "Report Route 53 records pointing at AWS resources that no longer exist in the inventory",
+		Run: func(cmd *cobra.Command, args []string) {
+			in, _ := cmd.Flags().GetString("in")
+			out, _ := cmd.Flags().GetString("out")
+			log.Printf("Creating dangling record report from %s at %s\n", in, out)
+			services, err := readInventoryFile(in)
+			if err != nil {
+				log.Printf("Error reading inventory: %v\n", err)
+				return
+			}
+			records := findDanglingRecords(services)
+			log.Printf("Found %d dangling records\n", len(records))
+			if err := writeToJsonFile(records, out); err != nil {
+				log.Printf("Error writing dangling record report: %v\n", err)
+			}
+		},
+	}
+	cmd.Flags().String("in", "", "Input path of the inventory file")
+	cmd.Flags().String("out", "", "Output path for the dangling record report")
+
+	cmd.MarkFlagRequired("in")
+	cmd.MarkFlagRequired("out")
+	cmd.MarkFlagFilename("in")
+	cmd.MarkFlagFilename("out")
+	return cmd
+}
+
+// listRoute53HostedZones retrieves hosted zones with their record sets, and the health checks of the account
+func listRoute53HostedZones(cfg aws.Config) ([]ResourceMetadata, error) {
+	svc := route53.NewFromConfig(cfg)
+
+	var resources []ResourceMetadata
+	paginator := route53.NewListHostedZonesPaginator(svc, &route53.ListHostedZonesInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return nil, err
+		}
+
+		for _, zone := range page.HostedZones {
+			metadata := map[string]interface{}{
+				"resource_type":         "hosted_zone",
+				"name":                  *zone.Name,
+				"resource_record_count": zone.ResourceRecordSetCount,
+				"records":               getResourceRecordSets(svc, zone.Id),
+			}
+			if zone.Config != nil {
+				metadata["private_zone"] = zone.Config.PrivateZone
+				if zone.Config.Comment != nil {
+					metadata["comment"] = *zone.Config.Comment
+				}
+			}
+
+			resources = append(resources, ResourceMetadata{
+				ResourceID: *zone.Id,
+				MetaData:   metadata,
+			})
+		}
+	}
+
+	healthChecks, err := listHealthChecks(svc)
+	if err != nil {
+		return resources, &partialResourcesError{Step: "route53_health_checks", Err: fmt.Errorf("listing Route 53 health checks: %w", err)}
+	}
+	return append(resources, healthChecks...), nil
+}
+
+func getResourceRecordSets(svc *route53.Client, zoneID *string) []map[string]interface{} {
+	var records []map[string]interface{}
+	input := &route53.ListResourceRecordSetsInput{HostedZoneId: zoneID}
+	for {
+		result, err := svc.ListResourceRecordSets(context.TODO(), input)
+		if err != nil {
+			log.Printf("Error listing record sets of zone %s: %v\n", aws.ToString(zoneID), err)
+			return records
+		}
+
+		for _, recordSet := range result.ResourceRecordSets {
+			records = append(records, recordSetMetadata(recordSet))
+		}
+
+		if !result.IsTruncated {
+			break
+		}
+		input.StartRecordName = result.NextRecordName
+		input.StartRecordType = result.NextRecordType
+		input.StartRecordIdentifier = result.NextRecordIdentifier
+	}
+	return records
+}
+
+func recordSetMetadata(recordSet r53Types.ResourceRecordSet) map[string]interface{} {
+	var values []string
+	for _, record := range recordSet.ResourceRecords {
+		values = append(values, aws.ToString(record.Value))
+	}
+
+	metadata := map[string]interface{}{
+		"name":   aws.ToString(recordSet.Name),
+		"type":   recordSet.Type,
+		"values": values,
+	}
+	if recordSet.TTL != nil {
+		metadata["ttl"] = *recordSet.TTL
+	}
+	if recordSet.AliasTarget != nil {
+		metadata["alias_target"] = aws.ToString(recordSet.AliasTarget.DNSName)
+		metadata["evaluate_target_health"] = recordSet.AliasTarget.EvaluateTargetHealth
+	}
+	if recordSet.SetIdentifier != nil {
+		metadata["set_identifier"] = *recordSet.SetIdentifier
+	}
+	if recordSet.HealthCheckId != nil {
+		metadata["health_check_id"] = *recordSet.HealthCheckId
+	}
+	return metadata
+}
+
+// listHealthChecks retrieves health checks with the status reported by the Route 53 checkers
+func listHealthChecks(svc *route53.Client) ([]ResourceMetadata, error) {
+	var resources []ResourceMetadata
+	paginator := route53.NewListHealthChecksPaginator(svc, &route53.ListHealthChecksInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return nil, err
+		}
+
+		for _, check := range page.HealthChecks {
+			id := aws.ToString(check.Id)
+			metadata := map[string]interface{}{
+				"resource_type": "health_check",
+			}
+			if config := check.HealthCheckConfig; config != nil {
+				metadata["type"] = config.Type
+				metadata["fqdn"] = aws.ToString(config.FullyQualifiedDomainName)
+				metadata["ip_address"] = aws.ToString(config.IPAddress)
+				metadata["port"] = aws.ToInt32(config.Port)
+				metadata["resource_path"] = aws.ToString(config.ResourcePath)
+				metadata["request_interval"] = aws.ToInt32(config.RequestInterval)
+				metadata["failure_threshold"] = aws.ToInt32(config.FailureThreshold)
+
+				// Calculated and CloudWatch alarm checks have no checkers to report a status
+				if config.Type != r53Types.HealthCheckTypeCalculated && config.Type != r53Types.HealthCheckTypeCloudwatchMetric {
+					checkers, healthy, ok := getHealthCheckStatus(svc, id)
+					if ok {
+						metadata["checkers"] = checkers
+						metadata["healthy_checkers"] = healthy
+						metadata["healthy"] = healthy*100 > checkers*healthyCheckerPercent
+					}
+				}
+			}
+			// Route 53 publishes health check metrics in us-east-1
+			setCloudWatchRef(metadata, "AWS/Route53", map[string]string{"HealthCheckId": id})
+
+			resources = append(resources, ResourceMetadata{
+				ResourceID: id,
+				MetaData:   metadata,
+			})
+		}
+	}
+	return resources, nil
+}
+
+// getHealthCheckStatus returns the number of checkers and how many of them last reported success
+func getHealthCheckStatus(svc *route53.Client, healthCheckID string) (int, int, bool) {
+	result, err := svc.GetHealthCheckStatus(context.TODO(), &route53.GetHealthCheckStatusInput{
+		HealthCheckId: aws.String(healthCheckID),
+	})
+	if err != nil || len(result.HealthCheckObservations) == 0 {
+		return 0, 0, false
+	}
+
+	healthy := 0
+	for _, observation := range result.HealthCheckObservations {
+		if observation.StatusReport != nil && strings.HasPrefix(aws.ToString(observation.StatusReport.Status), "Success") {
+			healthy++
+		}
+	}
+	return len(result.HealthCheckObservations), healthy, true
+}
+
+// attachRecordTargets resolves alias and CNAME targets of every hosted zone record to the ELB, CloudFront,
+// RDS, Redshift and Global Accelerator resources of the inventory
+func attachRecordTargets(services []ServiceMetadata) {
+	type target struct {
+		serviceName string
+		resourceID  string
+	}
+	targets := make(map[string]target)
+	for _, service := range services {
+		for _, resource := range service.Resources {
+			for _, key := range []string{"dns_name", "domain_name", "endpoint", "reader_endpoint"} {
+				if name := normalizeDNSName(metadataString(resource.MetaData, key)); name != "" {
+					targets[name] = target{serviceName: service.ServiceName, resourceID: resource.ResourceID}
+				}
+			}
+		}
+	}
+
+	for _, record := range hostedZoneRecords(services) {
+		target, ok := targets[normalizeDNSName(recordTarget(record))]
+		if !ok {
+			continue
+		}
+		record["target_resource_id"] = target.resourceID
+		record["target_service_name"] = target.serviceName
+	}
+}
+
+// findDanglingRecords lists records whose target looks like an AWS resource of a service the inventory covers,
+// but matches none of its resources. Targets of services missing from the inventory cannot be checked.
+func findDanglingRecords(services []ServiceMetadata) []DanglingRecord {
+	attachRecordTargets(services)
+
+	covered := make(map[string]bool)
+	for _, service := range services {
+		if code, ok := mapServiceNameToCode(service.ServiceName); ok {
+			covered[code] = true
+		}
+	}
+	// Aurora clusters are collected under either service code
+	covered["rds"] = covered["rds"] || covered["aurora"]
+
+	records := []DanglingRecord{}
+	for _, service := range services {
+		if code, _ := mapServiceNameToCode(service.ServiceName); code != "route53" {
+			continue
+		}
+		for _, zone := range service.Resources {
+			for _, record := range zoneRecords(zone.MetaData) {
+				if _, ok := record["target_resource_id"]; ok {
+					continue
+				}
+				target := normalizeDNSName(recordTarget(record))
+				code := awsTargetServiceCode(target)
+				if !covered[code] {
+					continue
+				}
+				records = append(records, DanglingRecord{
+					ZoneID:        zone.ResourceID,
+					ZoneName:      metadataString(zone.MetaData, "name"),
+					Name:          metadataString(record, "name"),
+					Type:          metadataString(record, "type"),
+					Target:        target,
+					TargetService: code,
+				})
+			}
+		}
+	}
+
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].Name < records[j].Name
+	})
+	return records
+}
+
+// hostedZoneRecords returns the records of every hosted zone in the inventory
+func hostedZoneRecords(services []ServiceMetadata) []map[string]interface{} {
+	var records []map[string]interface{}
+	for _, service := range services {
+		if code, _ := mapServiceNameToCode(service.ServiceName); code != "route53" {
+			continue
+		}
+		for _, zone := range service.Resources {
+			records = append(records, zoneRecords(zone.MetaData)...)
+		}
+	}
+	return records
+}
+
+// zoneRecords returns the records of a zone, whether collected or read back from an inventory file
+func zoneRecords(metadata map[string]interface{}) []map[string]interface{} {
+	switch records := metadata["records"].(type) {
+	case []map[string]interface{}:
+		return records
+	case []interface{}:
+		var result []map[string]interface{}
+		for _, record := range records {
+			if m, ok := record.(map[string]interface{}); ok {
+				result = append(result, m)
+			}
+		}
+		return result
+	}
+	return nil
+}
+
+// recordTarget returns the alias target of a record, or the value of a CNAME record
+func recordTarget(record map[string]interface{}) string {
+	if alias := metadataString(record, "alias_target"); alias != "" {
+		return alias
+	}
+	if metadataString(record, "type") != string(r53Types.RRTypeCname) {
+		return ""
+	}
+	switch values := record["values"].(type) {
+	case []string:
+		if len(values) > 0 {
+			return values[0]
+		}
+	case []interface{}:
+		if len(values) > 0 {
+			value, _ := values[0].(string)
+			return value
+		}
+	}
+	return ""
+}
+
+// normalizeDNSName lowercases a DNS name and strips the trailing dot, URL scheme and the dualstack prefix
+// Route 53 adds to load balancer aliases
+func normalizeDNSName(name string) string {
+	name = strings.ToLower(strings.TrimSuffix(name, "."))
+	name = strings.TrimPrefix(name, "https://")
+	name = strings.TrimPrefix(name, "dualstack.")
+	return name
+}
+
+// awsTargetServiceCode returns the service code of the AWS resource a DNS name belongs to
+func awsTargetServiceCode(name string) string {
+	switch {
+	case strings.Contains(name, ".elb.") && strings.HasSuffix(name, ".amazonaws.com"):
+		return "elb"
+	case strings.HasSuffix(name, ".cloudfront.net"):
+		return "cloudfront"
+	case strings.HasSuffix(name, ".rds.amazonaws.com"):
+		return "rds"
+	case strings.HasSuffix(name, ".redshift.amazonaws.com"):
+		return "redshift"
+	case strings.HasSuffix(name, ".awsglobalaccelerator.com"):
+		return "globalaccelerator"
+	}
+	return ""
+}
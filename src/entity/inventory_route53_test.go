@@ -0,0 +1,71 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	clbTypes "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancing/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindDanglingRecords(t *testing.T) {
+	services := []ServiceMetadata{
+		{ServiceName: "Amazon Route 53", Resources: []ResourceMetadata{
+			{ResourceID: "/hostedzone/Z1", MetaData: map[string]interface{}{
+				"name": "example.com.",
+				"records": []interface{}{
+					map[string]interface{}{"name": "app.example.com.", "type": "A",
+						"alias_target": "dualstack.web-123.eu-west-1.elb.amazonaws.com."},
+					map[string]interface{}{"name": "old.example.com.", "type": "A",
+						"alias_target": "gone-456.elb.eu-west-1.amazonaws.com."},
+					map[string]interface{}{"name": "classic.example.com.", "type": "A",
+						"alias_target": "dualstack.classic-789.eu-west-1.elb.amazonaws.com."},
+					map[string]interface{}{"name": "cdn.example.com.", "type": "CNAME",
+						"values": []interface{}{"d111.cloudfront.net"}},
+					map[string]interface{}{"name": "db.example.com.", "type": "CNAME",
+						"values": []interface{}{"db.abc.eu-west-1.rds.amazonaws.com"}},
+					map[string]interface{}{"name": "www.example.com.", "type": "CNAME",
+						"values": []interface{}{"example.github.io"}},
+				},
+			}},
+		}},
+		{ServiceName: "Amazon Elastic Load Balancing", Resources: []ResourceMetadata{
+			{ResourceID: "arn:web", MetaData: map[string]interface{}{"dns_name": "web-123.eu-west-1.elb.amazonaws.com"}},
+		}},
+		{ServiceName: "Amazon CloudFront", Resources: []ResourceMetadata{
+			{ResourceID: "E1", MetaData: map[string]interface{}{"domain_name": "d111.cloudfront.net"}},
+		}},
+	}
+
+	records := findDanglingRecords(services)
+
+	// RDS is not in the inventory, so the database record cannot be checked
+	assert.Len(t, records, 2)
+	assert.Equal(t, "classic.example.com.", records[0].Name)
+	assert.Equal(t, "classic-789.eu-west-1.elb.amazonaws.com", records[0].Target)
+	assert.Equal(t, "old.example.com.", records[1].Name)
+	assert.Equal(t, "elb", records[1].TargetService)
+	assert.Equal(t, "gone-456.elb.eu-west-1.amazonaws.com", records[1].Target)
+
+	// Classic load balancers are matched like application load balancers
+	services[1].Resources = append(services[1].Resources, ResourceMetadata{ResourceID: "classic", MetaData: classicLoadBalancerMetadata(clbTypes.LoadBalancerDescription{
+		LoadBalancerName: aws.String("classic"),
+		DNSName:          aws.String("classic-789.eu-west-1.elb.amazonaws.com"),
+	})})
+	records = findDanglingRecords(services)
+	assert.Len(t, records, 1)
+	assert.Equal(t, "old.example.com.", records[0].Name)
+	assert.Equal(t, "classic", zoneRecords(services[0].Resources[0].MetaData)[2]["target_resource_id"])
+
+	// The database record points at an instance endpoint once RDS is in the inventory
+	services = append(services, ServiceMetadata{ServiceName: "Amazon Relational Database Service", Resources: []ResourceMetadata{
+		{ResourceID: "db", MetaData: map[string]interface{}{"endpoint": "db.abc.eu-west-1.rds.amazonaws.com"}},
+	}})
+	records = findDanglingRecords(services)
+	assert.Len(t, records, 1)
+	assert.Equal(t, "db", zoneRecords(services[0].Resources[0].MetaData)[4]["target_resource_id"])
+
+	zoneRecords := zoneRecords(services[0].Resources[0].MetaData)
+	assert.Equal(t, "arn:web", zoneRecords[0]["target_resource_id"])
+	assert.Equal(t, "Amazon CloudFront", zoneRecords[3]["target_service_name"])
+}
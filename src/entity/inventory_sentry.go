@@ -0,0 +1,241 @@
+package entity
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Error counts cover a moving window
+func init() {
+	addVolatileMetadataKeys("errors")
+}
+
+// Period of the error volume recorded on each project
+const sentryStatsPeriod = "14d"
+
+// Sentry paginates with a Link header, the next page only has results when results="true"
+var sentryNextLink = regexp.MustCompile(`<([^>]+)>;\s*rel="next";\s*results="true"`)
+
+type sentryProject struct {
+	ID          string `json:"id"`
+	Slug        string `json:"slug"`
+	Name        string `json:"name"`
+	Platform    string `json:"platform"`
+	DateCreated string `json:"dateCreated"`
+	Teams       []struct {
+		Slug string `json:"slug"`
+	} `json:"teams"`
+}
+
+type sentryEnvironment struct {
+	Name     string `json:"name"`
+	IsHidden bool   `json:"isHidden"`
+}
+
+// sentryEnvironmentFile is the part of a storybook environment file, such as production.json, holding datasources
+type sentryEnvironmentFile struct {
+	Where []struct {
+		Name string                 `json:"name"`
+		Type string                 `json:"type"`
+		Vars map[string]interface{} `json:"vars"`
+	} `json:"where"`
+}
+
+// resolveSentryCredentials fills in the organization and token not given as flags, the token from SENTRY_AUTH_TOKEN
+// and both from the first sentry datasource of an environment file when one is given
+func resolveSentryCredentials(organization, token, environmentFile string) (string, string, error) {
+	if token == "" {
+		token = os.Getenv("SENTRY_AUTH_TOKEN")
+	}
+	if environmentFile == "" || (organization != "" && token != "") {
+		return organization, token, nil
+	}
+
+	data, err := os.ReadFile(environmentFile)
+	if err != nil {
+		return "", "", err
+	}
+	var environment sentryEnvironmentFile
+	if err := json.Unmarshal(data, &environment); err != nil {
+		return "", "", fmt.Errorf("failed to parse %s: %w", environmentFile, err)
+	}
+	for _, datasource := range environment.Where {
+		if !strings.EqualFold(datasource.Type, "sentry") {
+			continue
+		}
+		if organization == "" {
+			organization = metadataString(datasource.Vars, "organization")
+		}
+		if token == "" {
+			token = metadataString(datasource.Vars, "access_token")
+		}
+		return organization, token, nil
+	}
+	return "", "", fmt.Errorf("no sentry datasource in %s", environmentFile)
+}
+
+// sentryInventory lists the projects of a Sentry organization with their teams, environments and
+// accepted error volume, as one service with a resource per project. A project the token cannot read is
+// kept with the error, which is also returned in the error list.
+func sentryInventory(baseURL, organization, token string) ([]ServiceMetadata, []InventoryError, error) {
+	api := strings.TrimSuffix(baseURL, "/") + "/api/0"
+
+	projects, err := getSentryList[sentryProject](api+"/organizations/"+url.PathEscape(organization)+"/projects/", token)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	errorCounts, err := getSentryErrorCounts(api, organization, token)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var resources []ResourceMetadata
+	var errs []InventoryError
+	for _, project := range projects {
+		metadata, err := sentryProjectMetadata(api, organization, token, project)
+		if err != nil {
+			err = fmt.Errorf("project %s: %w", project.Slug, err)
+			errs = append(errs, newInventoryError("sentry_project", "Sentry", "sentry", err))
+			metadata["error"] = err.Error()
+		}
+		metadata["errors"] = errorCounts[project.ID]
+
+		resources = append(resources, ResourceMetadata{
+			ResourceID: project.Slug,
+			MetaData:   metadata,
+		})
+	}
+
+	return []ServiceMetadata{{
+		ServiceName: "Sentry",
+		Resources:   resources,
+		MetaData: map[string]interface{}{
+			"source":       "sentry",
+			"sentry_url":   baseURL,
+			"organization": organization,
+		},
+	}}, errs, nil
+}
+
+// sentryProjectMetadata returns the metadata of a project with its teams and visible environments, the metadata
+// read before a failed call when it returns an error
+func sentryProjectMetadata(api, organization, token string, project sentryProject) (map[string]interface{}, error) {
+	projectPath := "/projects/" + url.PathEscape(organization) + "/" + url.PathEscape(project.Slug)
+	metadata := map[string]interface{}{
+		"resource_type": "project",
+		"project_id":    project.ID,
+		"name":          project.Name,
+		"platform":      project.Platform,
+		"date_created":  project.DateCreated,
+		"stats_period":  sentryStatsPeriod,
+	}
+
+	// The project list leaves out teams on older Sentry versions
+	if project.Teams == nil {
+		if err := getSentryAPI(api+projectPath+"/", token, &project); err != nil {
+			return metadata, err
+		}
+	}
+	var teams []string
+	for _, team := range project.Teams {
+		teams = append(teams, team.Slug)
+	}
+	metadata["teams"] = teams
+
+	environments, err := getSentryList[sentryEnvironment](api+projectPath+"/environments/", token)
+	if err != nil {
+		return metadata, err
+	}
+	var environmentNames []string
+	for _, environment := range environments {
+		if !environment.IsHidden {
+			environmentNames = append(environmentNames, environment.Name)
+		}
+	}
+	metadata["environments"] = environmentNames
+	return metadata, nil
+}
+
+// getSentryErrorCounts returns the accepted errors of each project over the stats period, keyed by project ID
+func getSentryErrorCounts(api, organization, token string) (map[string]int, error) {
+	query := url.Values{
+		"field":       {"sum(quantity)"},
+		"groupBy":     {"project"},
+		"category":    {"error"},
+		"outcome":     {"accepted"},
+		"statsPeriod": {sentryStatsPeriod},
+		"interval":    {"1d"},
+	}
+	var stats struct {
+		Groups []struct {
+			By     map[string]json.RawMessage `json:"by"`
+			Totals map[string]float64         `json:"totals"`
+		} `json:"groups"`
+	}
+	if err := getSentryAPI(api+"/organizations/"+url.PathEscape(organization)+"/stats_v2/?"+query.Encode(), token, &stats); err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int)
+	for _, group := range stats.Groups {
+		// Project IDs are strings in the project list and numbers too large for float64 in stats
+		projectID := strings.Trim(string(group.By["project"]), `"`)
+		counts[projectID] += int(group.Totals["sum(quantity)"])
+	}
+	return counts, nil
+}
+
+// getSentryList calls a Sentry API list endpoint and returns the items of every page
+func getSentryList[T any](endpoint, token string) ([]T, error) {
+	var items []T
+	for endpoint != "" {
+		var page []T
+		next, err := getSentryPage(endpoint, token, &page)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, page...)
+		endpoint = next
+	}
+	return items, nil
+}
+
+// getSentryAPI calls a Sentry API endpoint and decodes the response
+func getSentryAPI(endpoint, token string, target interface{}) error {
+	_, err := getSentryPage(endpoint, token, target)
+	return err
+}
+
+// getSentryPage decodes one response and returns the URL of the next page, empty on the last page
+func getSentryPage(endpoint, token string, target interface{}) (string, error) {
+	req, err := http.NewRequest("GET", endpoint, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("received non-OK HTTP status: %s", resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(target); err != nil {
+		return "", fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	if match := sentryNextLink.FindStringSubmatch(resp.Header.Get("Link")); match != nil {
+		return match[1], nil
+	}
+	return "", nil
+}
@@ -0,0 +1,84 @@
+package entity
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSentryInventory(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/api/0/organizations/acme/projects/":
+			if r.URL.Query().Get("cursor") == "" {
+				w.Header().Set("Link", `<`+server.URL+`/api/0/organizations/acme/projects/?cursor=1>; rel="next"; results="true"; cursor="1"`)
+				w.Write([]byte(`[{"id":"4504123456789012","slug":"web","name":"Web","platform":"javascript","teams":[{"slug":"frontend"}]}]`))
+				return
+			}
+			w.Header().Set("Link", `<`+server.URL+`/api/0/organizations/acme/projects/?cursor=2>; rel="next"; results="false"; cursor="2"`)
+			w.Write([]byte(`[{"id":"7","slug":"api","name":"API","platform":"go"},{"id":"8","slug":"billing","name":"Billing","teams":[]}]`))
+		case "/api/0/projects/acme/api/":
+			w.Write([]byte(`{"id":"7","slug":"api","name":"API","platform":"go","teams":[{"slug":"backend"}]}`))
+		case "/api/0/projects/acme/web/environments/":
+			w.Write([]byte(`[{"name":"production","isHidden":false},{"name":"old","isHidden":true}]`))
+		case "/api/0/projects/acme/api/environments/":
+			w.Write([]byte(`[{"name":"staging","isHidden":false}]`))
+		case "/api/0/projects/acme/billing/environments/":
+			w.WriteHeader(http.StatusForbidden)
+		case "/api/0/organizations/acme/stats_v2/":
+			w.Write([]byte(`{"groups":[{"by":{"project":4504123456789012},"totals":{"sum(quantity)":1520}}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	services, errs, err := sentryInventory(server.URL, "acme", "secret")
+
+	assert.NoError(t, err)
+	assert.Len(t, services, 1)
+	projects := services[0].Resources
+	assert.Len(t, projects, 3)
+	assert.Equal(t, "web", projects[0].ResourceID)
+	assert.Equal(t, []string{"frontend"}, projects[0].MetaData["teams"])
+	assert.Equal(t, []string{"production"}, projects[0].MetaData["environments"])
+	assert.Equal(t, 1520, projects[0].MetaData["errors"])
+	assert.Equal(t, []string{"backend"}, projects[1].MetaData["teams"])
+	assert.Equal(t, 0, projects[1].MetaData["errors"])
+
+	// The token cannot read the billing project, which is kept with the error
+	assert.Equal(t, "project billing: received non-OK HTTP status: 403 Forbidden", projects[2].MetaData["error"])
+	assert.Len(t, errs, 1)
+	assert.Equal(t, "sentry_project", errs[0].Step)
+
+	_, _, err = sentryInventory(server.URL, "acme", "wrong")
+	assert.Error(t, err)
+}
+
+func TestResolveSentryCredentials(t *testing.T) {
+	environmentFile := filepath.Join(t.TempDir(), "production.json")
+	assert.NoError(t, os.WriteFile(environmentFile, []byte(`{"where":[
+		{"name":"prometheus","type":"Prometheus","vars":{"prom_address":"http://127.0.0.1:9090"}},
+		{"name":"sentry_demo","type":"sentry","vars":{"organization":"acme","access_token":"from-file"}}]}`), 0644))
+
+	t.Setenv("SENTRY_AUTH_TOKEN", "")
+	organization, token, err := resolveSentryCredentials("", "", environmentFile)
+	assert.NoError(t, err)
+	assert.Equal(t, "acme", organization)
+	assert.Equal(t, "from-file", token)
+
+	t.Setenv("SENTRY_AUTH_TOKEN", "from-env")
+	organization, token, err = resolveSentryCredentials("other", "", environmentFile)
+	assert.NoError(t, err)
+	assert.Equal(t, "other", organization)
+	assert.Equal(t, "from-env", token)
+}
@@ -0,0 +1,191 @@
+package entity
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/spf13/cobra"
+)
+
+func serveInventoryCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Collect the inventory on an interval and expose it as Prometheus metrics",
+		Run: func(cmd *cobra.Command, args []string) {
+			listen, _ := cmd.Flags().GetString("listen")
+			interval, _ := cmd.Flags().GetDuration("interval")
+			discover, _ := cmd.Flags().GetString("discover")
+			window, _ := cmd.Flags().GetDuration("window")
+			if discover != "cost" && discover != "cloudtrail" {
+				log.Printf("Unknown discovery source %s, use cost or cloudtrail\n", discover)
+				return
+			}
+			log.Printf("Serving inventory metrics on %s/metrics\n", listen)
+			err := serveInventory(listen, interval, inventoryOptions{Discover: discover, Window: window, AWS: awsConfigOptionsFromFlags(cmd)})
+			if err != nil {
+				log.Printf("Error serving inventory metrics: %v\n", err)
+			}
+		},
+	}
+	cmd.Flags().String("listen", ":9108", "Address to serve /metrics on")
+	cmd.Flags().Duration("interval", time.Hour, "Time between two inventory collections")
+	cmd.Flags().String("discover", "cost", "How to find the services to collect: cost, or cloudtrail to add services with recent write events")
+	cmd.Flags().Duration("window", 7*24*time.Hour, "CloudTrail window used with --discover=cloudtrail")
+	addAWSConfigFlags(cmd)
+	return cmd
+}
+
+// inventoryExporter holds the last successful collection and the errors seen since the exporter started
+type inventoryExporter struct {
+	mu          sync.Mutex
+	services    []ServiceMetadata
+	runs        []collectorRun
+	collectedAt time.Time
+	duration    time.Duration
+	// Collector errors per service
+	collectorErrors map[string]int
+	// Collections that failed as a whole, e.g. when Cost Explorer is unavailable
+	collectionErrors int
+}
+
+func serveInventory(listen string, interval time.Duration, options inventoryOptions) error {
+	cfg, err := loadAWSConfig(options.AWS)
+	if err != nil {
+		return err
+	}
+
+	exporter := &inventoryExporter{collectorErrors: make(map[string]int)}
+	go func() {
+		for {
+			exporter.collect(cfg, options)
+			time.Sleep(interval)
+		}
+	}()
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", exporter)
+	return http.ListenAndServe(listen, mux)
+}
+
+func (e *inventoryExporter) collect(cfg aws.Config, options inventoryOptions) {
+	start := time.Now()
+	services, runs, _, err := collectInventory(cfg, options)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	// Keep serving the last successful collection when this one fails
+	if err != nil {
+		log.Println("Error collecting inventory:", err)
+		e.collectionErrors++
+		return
+	}
+	for _, run := range runs {
+		if run.Err != nil {
+			e.collectorErrors[run.ServiceName]++
+		}
+	}
+	e.services = services
+	e.runs = runs
+	e.collectedAt = start
+	e.duration = time.Since(start)
+	log.Printf("Collected %d services in %s\n", len(services), e.duration.Round(time.Second))
+}
+
+func (e *inventoryExporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	e.mu.Lock()
+	metrics := renderInventoryMetrics(e.services, e.runs, e.collectorErrors, e.collectionErrors, e.collectedAt, e.duration)
+	e.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	fmt.Fprint(w, metrics)
+}
+
+// renderInventoryMetrics renders resource counts, untagged resources and cost per service, and how each
+// collector did, in the Prometheus text exposition format
+func renderInventoryMetrics(services []ServiceMetadata, runs []collectorRun, collectorErrors map[string]int,
+	collectionErrors int, collectedAt time.Time, duration time.Duration) string {
+	var b strings.Builder
+	metric := func(name, help, kind string) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+	}
+
+	if len(services) > 0 {
+		metric("autoptic_inventory_resources", "Resources found by the inventory collectors.", "gauge")
+		for _, service := range services {
+			fmt.Fprintf(&b, "autoptic_inventory_resources%s %d\n", serviceLabels(service), len(service.Resources))
+		}
+
+		metric("autoptic_inventory_untagged_resources", "Taggable resources without any tags.", "gauge")
+		for _, service := range services {
+			untagged := 0
+			for _, resource := range service.Resources {
+				if _, ok := resource.MetaData["tags"]; ok && len(resourceTags(resource.MetaData)) == 0 {
+					untagged++
+				}
+			}
+			fmt.Fprintf(&b, "autoptic_inventory_untagged_resources%s %d\n", serviceLabels(service), untagged)
+		}
+
+		metric("autoptic_inventory_service_cost_dollars", "Unblended cost of the service over the last month.", "gauge")
+		for _, service := range services {
+			if cost, ok := service.MetaData["monthly_cost"].(float64); ok {
+				fmt.Fprintf(&b, "autoptic_inventory_service_cost_dollars%s %g\n", serviceLabels(service), cost)
+			}
+		}
+	}
+
+	if len(runs) > 0 {
+		metric("autoptic_inventory_collector_duration_seconds", "Time the collector of a service took in the last collection.", "gauge")
+		for _, run := range runs {
+			fmt.Fprintf(&b, "autoptic_inventory_collector_duration_seconds%s %g\n",
+				formatLabels(map[string]string{"service": run.ServiceName}), run.Duration.Seconds())
+		}
+		metric("autoptic_inventory_collector_up", "Whether the collector of a service succeeded in the last collection.", "gauge")
+		for _, run := range runs {
+			up := 1
+			if run.Err != nil {
+				up = 0
+			}
+			fmt.Fprintf(&b, "autoptic_inventory_collector_up%s %d\n", formatLabels(map[string]string{"service": run.ServiceName}), up)
+		}
+	}
+
+	metric("autoptic_inventory_collector_errors_total", "Collector errors of a service since the exporter started.", "counter")
+	for _, name := range sortedKeys(collectorErrors) {
+		fmt.Fprintf(&b, "autoptic_inventory_collector_errors_total%s %d\n", formatLabels(map[string]string{"service": name}), collectorErrors[name])
+	}
+	metric("autoptic_inventory_collection_errors_total", "Collections that failed before any collector ran.", "counter")
+	fmt.Fprintf(&b, "autoptic_inventory_collection_errors_total %d\n", collectionErrors)
+
+	if !collectedAt.IsZero() {
+		metric("autoptic_inventory_last_collection_timestamp_seconds", "Time the last successful collection started.", "gauge")
+		fmt.Fprintf(&b, "autoptic_inventory_last_collection_timestamp_seconds %d\n", collectedAt.Unix())
+		metric("autoptic_inventory_collection_duration_seconds", "Time the last successful collection took.", "gauge")
+		fmt.Fprintf(&b, "autoptic_inventory_collection_duration_seconds %g\n", duration.Seconds())
+	}
+	return b.String()
+}
+
+func serviceLabels(service ServiceMetadata) string {
+	return formatLabels(map[string]string{
+		"service":    service.ServiceName,
+		"region":     metadataString(service.MetaData, "region"),
+		"account_id": metadataString(service.MetaData, "account_id"),
+	})
+}
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// formatLabels renders a label set sorted by name, escaping values as the text format requires
+func formatLabels(labels map[string]string) string {
+	var pairs []string
+	for _, name := range sortedKeys(labels) {
+		pairs = append(pairs, name+`="`+labelValueEscaper.Replace(labels[name])+`"`)
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
@@ -0,0 +1,63 @@
+package entity
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	costTypes "github.com/aws/aws-sdk-go-v2/service/costexplorer/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInventoryExporter(t *testing.T) {
+	exporter := &inventoryExporter{
+		services: []ServiceMetadata{
+			{ServiceName: "Amazon Simple Storage Service",
+				MetaData: map[string]interface{}{"region": "eu-west-1", "account_id": "123", "monthly_cost": 12.5},
+				Resources: []ResourceMetadata{
+					{ResourceID: "tagged", MetaData: map[string]interface{}{"tags": map[string]string{"team": "data"}}},
+					{ResourceID: "untagged", MetaData: map[string]interface{}{"tags": map[string]string{}}},
+				}},
+		},
+		runs: []collectorRun{
+			{ServiceName: "Amazon Simple Storage Service", Duration: 1500 * time.Millisecond},
+			{ServiceName: `Amazon "Quoted"`, Err: errors.New("access denied")},
+		},
+		collectedAt:     time.Unix(1790000000, 0),
+		duration:        2 * time.Second,
+		collectorErrors: map[string]int{`Amazon "Quoted"`: 3},
+	}
+
+	recorder := httptest.NewRecorder()
+	exporter.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	body := recorder.Body.String()
+
+	labels := `{account_id="123",region="eu-west-1",service="Amazon Simple Storage Service"}`
+	assert.Contains(t, body, "# TYPE autoptic_inventory_resources gauge\n")
+	assert.Contains(t, body, "autoptic_inventory_resources"+labels+" 2\n")
+	assert.Contains(t, body, "autoptic_inventory_untagged_resources"+labels+" 1\n")
+	assert.Contains(t, body, "autoptic_inventory_service_cost_dollars"+labels+" 12.5\n")
+	assert.Contains(t, body, `autoptic_inventory_collector_duration_seconds{service="Amazon Simple Storage Service"} 1.5`+"\n")
+	assert.Contains(t, body, `autoptic_inventory_collector_up{service="Amazon \"Quoted\""} 0`+"\n")
+	assert.Contains(t, body, `autoptic_inventory_collector_errors_total{service="Amazon \"Quoted\""} 3`+"\n")
+	assert.Contains(t, body, "autoptic_inventory_last_collection_timestamp_seconds 1790000000\n")
+}
+
+func TestSumServiceCosts(t *testing.T) {
+	group := func(service, amount string) costTypes.Group {
+		return costTypes.Group{Keys: []string{service},
+			Metrics: map[string]costTypes.MetricValue{"UnblendedCost": {Amount: aws.String(amount)}}}
+	}
+	services, costs := sumServiceCosts([]costTypes.ResultByTime{
+		{Groups: []costTypes.Group{group("Amazon DynamoDB", "10.5"), group("AWS Lambda", "1")}},
+		{Groups: []costTypes.Group{group("Amazon DynamoDB", "4.5"), group("Amazon Simple Queue Service", "2")}},
+	})
+	assert.Equal(t, []string{"Amazon DynamoDB", "AWS Lambda", "Amazon Simple Queue Service"}, services)
+	assert.Equal(t, map[string]float64{"Amazon DynamoDB": 15, "AWS Lambda": 1, "Amazon Simple Queue Service": 2}, costs)
+
+	services, costs = sumServiceCosts(nil)
+	assert.Empty(t, services)
+	assert.Empty(t, costs)
+}
@@ -0,0 +1,199 @@
+package entity
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var terraformInvalidNameChars = regexp.MustCompile(`[^a-z0-9_-]+`)
+
+// terraformImport is an import block bringing an existing resource under a Terraform resource address
+type terraformImport struct {
+	ResourceType string
+	Name         string
+	ID           string
+	// Region of regional resources, imported with the provider of that region
+	Region string
+}
+
+// writeTerraformImports writes one file of import blocks per account and service, <out>/<account_id>/<service_code>.tf.
+// Inventories of several regions of the same account share the file, regional resources are imported with a
+// provider per region declared in <out>/<account_id>/providers.tf. Global resources are imported with the default
+// provider declared there as well.
+func writeTerraformImports(services []ServiceMetadata, out string) error {
+	files := make(map[string]*strings.Builder)
+	// The files of an account are one Terraform module, names and imports are unique across them
+	names := make(map[string]map[string]int)
+	regions := make(map[string]map[string]bool)
+	global := make(map[string]bool)
+	var paths []string
+	for _, service := range services {
+		code, _ := mapServiceNameToCode(service.ServiceName)
+		region := metadataString(service.MetaData, "region")
+		imports := terraformImports(code, region, service.Resources)
+		if len(imports) == 0 {
+			continue
+		}
+
+		accountID := metadataString(service.MetaData, "account_id")
+		if _, ok := names[accountID]; !ok {
+			names[accountID] = make(map[string]int)
+			regions[accountID] = make(map[string]bool)
+		}
+		rendered := renderTerraformImports(imports, names[accountID])
+		if rendered == "" {
+			continue
+		}
+		for _, block := range imports {
+			if block.Region != "" {
+				regions[accountID][block.Region] = true
+			} else {
+				global[accountID] = true
+			}
+		}
+
+		path := filepath.Join(out, accountID, code+".tf")
+		if _, ok := files[path]; !ok {
+			files[path] = &strings.Builder{}
+			paths = append(paths, path)
+		}
+		fmt.Fprintf(files[path], "# %s, account %s, region %s\n", service.ServiceName, accountID, region)
+		files[path].WriteString(rendered)
+		files[path].WriteString("\n")
+	}
+
+	for _, accountID := range sortedKeys(regions) {
+		if len(regions[accountID]) == 0 && !global[accountID] {
+			continue
+		}
+		path := filepath.Join(out, accountID, "providers.tf")
+		files[path] = &strings.Builder{}
+		paths = append(paths, path)
+		var blocks []string
+		if global[accountID] {
+			// The region of the default provider comes from the environment, like a plain terraform plan
+			blocks = append(blocks, "provider \"aws\" {\n}\n")
+		}
+		for _, region := range sortedKeys(regions[accountID]) {
+			blocks = append(blocks, fmt.Sprintf("provider \"aws\" {\n  alias  = %q\n  region = %q\n}\n", terraformProviderAlias(region), region))
+		}
+		files[path].WriteString(strings.Join(blocks, "\n"))
+	}
+
+	for _, path := range paths {
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, []byte(files[path].String()), 0644); err != nil {
+			return err
+		}
+		log.Printf("Wrote import blocks to %s\n", path)
+	}
+	return nil
+}
+
+// terraformImports maps the resources of a service to import blocks, for the resource types Terraform can import
+// by the ID the collectors record. Resources of other services are skipped. S3 buckets and Route 53 hosted zones
+// are listed the same in every region and are imported without a region.
+func terraformImports(code, region string, resources []ResourceMetadata) []terraformImport {
+	var imports []terraformImport
+	add := func(resourceType, name, id string) {
+		imports = append(imports, terraformImport{ResourceType: resourceType, Name: terraformName(name), ID: id, Region: region})
+	}
+	addGlobal := func(resourceType, name, id string) {
+		imports = append(imports, terraformImport{ResourceType: resourceType, Name: terraformName(name), ID: id})
+	}
+
+	for _, resource := range resources {
+		resourceType := metadataString(resource.MetaData, "resource_type")
+		switch code {
+		case "ec2":
+			if metadataString(resource.MetaData, "state") == "terminated" {
+				continue
+			}
+			add("aws_instance", resource.ResourceID, resource.ResourceID)
+		case "s3":
+			addGlobal("aws_s3_bucket", resource.ResourceID, resource.ResourceID)
+		case "dynamodb":
+			add("aws_dynamodb_table", resource.ResourceID, resource.ResourceID)
+		case "lambda":
+			add("aws_lambda_function", resource.ResourceID, resource.ResourceID)
+		case "rds", "aurora":
+			// Clusters are keyed by ARN and imported by identifier
+			if resourceType == "cluster" {
+				name := metadataString(resource.MetaData, "name")
+				add("aws_rds_cluster", name, name)
+			} else if metadataString(resource.MetaData, "db_cluster_identifier") != "" {
+				add("aws_rds_cluster_instance", resource.ResourceID, resource.ResourceID)
+			} else {
+				add("aws_db_instance", resource.ResourceID, resource.ResourceID)
+			}
+		case "elb":
+			// Classic load balancers are imported by name
+			if resourceType == "classic_load_balancer" {
+				add("aws_elb", resource.ResourceID, resource.ResourceID)
+				continue
+			}
+			// arn:aws:elasticloadbalancing:region:account:loadbalancer/app/name/id
+			parts := strings.Split(loadBalancerDimension(resource.ResourceID), "/")
+			name := resource.ResourceID
+			if len(parts) == 3 {
+				name = parts[1]
+			}
+			add("aws_lb", name, resource.ResourceID)
+		case "route53":
+			if resourceType == "health_check" {
+				continue
+			}
+			addGlobal("aws_route53_zone", metadataString(resource.MetaData, "name"), strings.TrimPrefix(resource.ResourceID, "/hostedzone/"))
+		}
+	}
+	return imports
+}
+
+// renderTerraformImports renders import blocks, numbering names already used in the account
+// since addresses must be unique within a resource type. Numbered names are reserved as well, so they do not
+// collide with a resource whose own name ends in a number. Global resources like S3 buckets show up in the
+// inventory of every region and are imported once, RDS clusters show up under both RDS and Aurora.
+func renderTerraformImports(imports []terraformImport, names map[string]int) string {
+	var b strings.Builder
+	for _, block := range imports {
+		imported := "id:" + block.ResourceType + "/" + block.Region + "/" + block.ID
+		if names[imported] > 0 {
+			continue
+		}
+		names[imported]++
+
+		name := block.Name
+		for n := 2; names[block.ResourceType+"."+name] > 0; n++ {
+			name = fmt.Sprintf("%s_%d", block.Name, n)
+		}
+		names[block.ResourceType+"."+name]++
+		if block.Region == "" {
+			fmt.Fprintf(&b, "\nimport {\n  to = %s.%s\n  id = %q\n}\n", block.ResourceType, name, block.ID)
+			continue
+		}
+		fmt.Fprintf(&b, "\nimport {\n  to       = %s.%s\n  id       = %q\n  provider = aws.%s\n}\n", block.ResourceType, name, block.ID,
+			terraformProviderAlias(block.Region))
+	}
+	return b.String()
+}
+
+// terraformProviderAlias names the provider of a region, e.g. eu_west_1
+func terraformProviderAlias(region string) string {
+	return strings.ReplaceAll(region, "-", "_")
+}
+
+// terraformName turns a resource ID or name into a valid Terraform resource name
+func terraformName(id string) string {
+	name := strings.TrimSuffix(strings.ToLower(id), ".")
+	name = strings.Trim(terraformInvalidNameChars.ReplaceAllString(name, "_"), "_")
+	if name == "" || (name[0] >= '0' && name[0] <= '9') || name[0] == '-' {
+		name = "r_" + name
+	}
+	return name
+}
@@ -0,0 +1,143 @@
+package entity
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTerraformImports(t *testing.T) {
+	imports := terraformImports("rds", "eu-west-1", []ResourceMetadata{
+		{ResourceID: "orders-db", MetaData: map[string]interface{}{"resource_type": "instance"}},
+		{ResourceID: "arn:aws:rds:eu-west-1:123:cluster:orders", MetaData: map[string]interface{}{"resource_type": "cluster", "name": "orders"}},
+		{ResourceID: "orders-1", MetaData: map[string]interface{}{"resource_type": "instance", "db_cluster_identifier": "orders"}},
+	})
+	assert.Equal(t, []terraformImport{
+		{ResourceType: "aws_db_instance", Name: "orders-db", ID: "orders-db", Region: "eu-west-1"},
+		{ResourceType: "aws_rds_cluster", Name: "orders", ID: "orders", Region: "eu-west-1"},
+		{ResourceType: "aws_rds_cluster_instance", Name: "orders-1", ID: "orders-1", Region: "eu-west-1"},
+	}, imports)
+
+	imports = terraformImports("route53", "us-east-1", []ResourceMetadata{
+		{ResourceID: "/hostedzone/Z123", MetaData: map[string]interface{}{"resource_type": "hosted_zone", "name": "example.com."}},
+		{ResourceID: "hc-1", MetaData: map[string]interface{}{"resource_type": "health_check"}},
+	})
+	assert.Equal(t, []terraformImport{{ResourceType: "aws_route53_zone", Name: "example_com", ID: "Z123"}}, imports)
+
+	imports = terraformImports("elb", "eu-west-1", []ResourceMetadata{
+		{ResourceID: "arn:aws:elasticloadbalancing:eu-west-1:123:loadbalancer/app/web/abc", MetaData: map[string]interface{}{}},
+		{ResourceID: "legacy-web", MetaData: map[string]interface{}{"resource_type": "classic_load_balancer"}},
+	})
+	assert.Equal(t, "web", imports[0].Name)
+	assert.Equal(t, terraformImport{ResourceType: "aws_elb", Name: "legacy-web", ID: "legacy-web", Region: "eu-west-1"}, imports[1])
+
+	assert.Nil(t, terraformImports("sqs", "eu-west-1", []ResourceMetadata{{ResourceID: "queue"}}))
+	assert.Equal(t, "r_1bucket", terraformName("1bucket"))
+}
+
+func TestWriteTerraformImports(t *testing.T) {
+	out := t.TempDir()
+	bucket := ResourceMetadata{ResourceID: "assets", MetaData: map[string]interface{}{}}
+	services := []ServiceMetadata{
+		{ServiceName: "Amazon Simple Storage Service", MetaData: map[string]interface{}{"account_id": "123", "region": "eu-west-1"},
+			Resources: []ResourceMetadata{bucket}},
+		{ServiceName: "Amazon Simple Storage Service", MetaData: map[string]interface{}{"account_id": "123", "region": "us-east-1"},
+			Resources: []ResourceMetadata{bucket, {ResourceID: "assets.logs", MetaData: map[string]interface{}{}}}},
+	}
+
+	assert.NoError(t, writeTerraformImports(services, out))
+
+	data, err := os.ReadFile(filepath.Join(out, "123", "s3.tf"))
+	assert.NoError(t, err)
+	assert.Equal(t, `# Amazon Simple Storage Service, account 123, region eu-west-1
+
+import {
+  to = aws_s3_bucket.assets
+  id = "assets"
+}
+
+# Amazon Simple Storage Service, account 123, region us-east-1
+
+import {
+  to = aws_s3_bucket.assets_logs
+  id = "assets.logs"
+}
+
+`, string(data))
+
+	// Buckets are imported with the default provider
+	data, err = os.ReadFile(filepath.Join(out, "123", "providers.tf"))
+	assert.NoError(t, err)
+	assert.Equal(t, "provider \"aws\" {\n}\n", string(data))
+}
+
+func TestRenderTerraformImportsNames(t *testing.T) {
+	names := make(map[string]int)
+	rendered := renderTerraformImports([]terraformImport{
+		{ResourceType: "aws_dynamodb_table", Name: "orders", ID: "orders", Region: "eu-west-1"},
+		{ResourceType: "aws_dynamodb_table", Name: "orders", ID: "orders", Region: "us-east-1"},
+		// Named like the second orders table
+		{ResourceType: "aws_dynamodb_table", Name: "orders_2", ID: "orders_2", Region: "eu-west-1"},
+		{ResourceType: "aws_dynamodb_table", Name: "orders", ID: "orders", Region: "eu-central-1"},
+	}, names)
+	assert.Contains(t, rendered, "aws_dynamodb_table.orders\n")
+	assert.Contains(t, rendered, "aws_dynamodb_table.orders_2\n")
+	assert.Contains(t, rendered, "aws_dynamodb_table.orders_2_2\n")
+	assert.Contains(t, rendered, "aws_dynamodb_table.orders_3\n")
+}
+
+func TestWriteTerraformImportsRegions(t *testing.T) {
+	out := t.TempDir()
+	cluster := ResourceMetadata{ResourceID: "arn:aws:rds:eu-west-1:123:cluster:orders",
+		MetaData: map[string]interface{}{"resource_type": "cluster", "name": "orders"}}
+	services := []ServiceMetadata{
+		{ServiceName: "Amazon Relational Database Service", MetaData: map[string]interface{}{"account_id": "123", "region": "eu-west-1"},
+			Resources: []ResourceMetadata{cluster}},
+		{ServiceName: "Amazon Aurora", MetaData: map[string]interface{}{"account_id": "123", "region": "eu-west-1"},
+			Resources: []ResourceMetadata{cluster}},
+		{ServiceName: "Amazon DynamoDB", MetaData: map[string]interface{}{"account_id": "123", "region": "eu-west-1"},
+			Resources: []ResourceMetadata{{ResourceID: "orders", MetaData: map[string]interface{}{}}}},
+		{ServiceName: "Amazon DynamoDB", MetaData: map[string]interface{}{"account_id": "123", "region": "us-east-1"},
+			Resources: []ResourceMetadata{{ResourceID: "orders", MetaData: map[string]interface{}{}}}},
+	}
+
+	assert.NoError(t, writeTerraformImports(services, out))
+
+	_, err := os.Stat(filepath.Join(out, "123", "aurora.tf"))
+	assert.True(t, os.IsNotExist(err), "the cluster is imported once, from rds.tf")
+
+	data, err := os.ReadFile(filepath.Join(out, "123", "dynamodb.tf"))
+	assert.NoError(t, err)
+	assert.Equal(t, `# Amazon DynamoDB, account 123, region eu-west-1
+
+import {
+  to       = aws_dynamodb_table.orders
+  id       = "orders"
+  provider = aws.eu_west_1
+}
+
+# Amazon DynamoDB, account 123, region us-east-1
+
+import {
+  to       = aws_dynamodb_table.orders_2
+  id       = "orders"
+  provider = aws.us_east_1
+}
+
+`, string(data))
+
+	data, err = os.ReadFile(filepath.Join(out, "123", "providers.tf"))
+	assert.NoError(t, err)
+	assert.Equal(t, `provider "aws" {
+  alias  = "eu_west_1"
+  region = "eu-west-1"
+}
+
+provider "aws" {
+  alias  = "us_east_1"
+  region = "us-east-1"
+}
+`, string(data))
+}
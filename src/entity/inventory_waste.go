@@ -0,0 +1,449 @@
+package entity
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	cwTypes "github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/aws/aws-sdk-go-v2/service/costexplorer"
+	costTypes "github.com/aws/aws-sdk-go-v2/service/costexplorer/types"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/spf13/cobra"
+)
+
+// Number of days without connections after which an RDS instance is considered idle
+const rdsIdleDays = 7
+
+// WasteItem is a resource that costs money without doing useful work
+type WasteItem struct {
+	ServiceName          string                 `json:"service_name"`
+	ResourceID           string                 `json:"resource_id"`
+	Reason               string                 `json:"reason"`
+	EstimatedMonthlyCost *float64               `json:"estimated_monthly_cost,omitempty"`
+	MetaData             map[string]interface{} `json:"metadata"`
+}
+
+func wasteInventoryCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "waste",
+		Short: "Report idle and unused resources with their estimated monthly cost",
+		Run: func(cmd *cobra.Command, args []string) {
+			out, _ := cmd.Flags().GetString("out")
+			log.Printf("Creating waste report at %s\n", out)
+			makeWasteReport(out, awsConfigOptionsFromFlags(cmd))
+		},
+	}
+	cmd.Flags().String("out", "", "Output path for the waste report")
+	addAWSConfigFlags(cmd)
+
+	cmd.MarkFlagRequired("out")
+	cmd.MarkFlagFilename("out")
+	return cmd
+}
+
+func makeWasteReport(out string, awsOptions awsConfigOptions) {
+	cfg, err := loadAWSConfig(awsOptions)
+	if err != nil {
+		log.Println("Error loading config:", err)
+		return
+	}
+
+	var items []WasteItem
+
+	volumes, err := listEBSVolumes(cfg)
+	if err != nil {
+		log.Println("Error listing EBS volumes:", err)
+	}
+	items = append(items, findUnattachedVolumes(volumes)...)
+
+	instances, err := listEC2Instances(cfg)
+	if err != nil {
+		log.Println("Error listing EC2 instances:", err)
+	}
+	items = append(items, findStoppedInstancesWithStorage(instances)...)
+
+	elbs, err := listELBs(cfg)
+	if err != nil {
+		log.Println("Error listing ELBs:", err)
+	}
+	items = append(items, findLoadBalancersWithoutHealthyTargets(elbs)...)
+
+	addresses, err := listElasticIPs(cfg)
+	if err != nil {
+		log.Println("Error listing Elastic IPs:", err)
+	}
+	items = append(items, findUnassociatedElasticIPs(addresses)...)
+
+	dbInstances, err := listRDSInstances(cfg)
+	if err != nil {
+		log.Println("Error listing RDS instances:", err)
+	}
+	items = append(items, findIdleRDSInstances(cfg, dbInstances)...)
+
+	logGroups, err := listLogGroups(cfg)
+	if err != nil {
+		log.Println("Error listing CloudWatch log groups:", err)
+	}
+	items = append(items, findLogGroupsWithInfiniteRetention(logGroups)...)
+	items = append(items, findIdleLogGroups(logGroups)...)
+
+	// Cost Explorer resource level data is opt-in, so the report is still written without it
+	costs, err := getResourceMonthlyCosts(cfg)
+	if err != nil {
+		log.Println("Error getting resource costs, estimates will be omitted:", err)
+	}
+	applyWasteCosts(items, costs)
+
+	var total float64
+	for _, item := range items {
+		if item.EstimatedMonthlyCost != nil {
+			total += *item.EstimatedMonthlyCost
+		}
+	}
+	log.Printf("Found %d wasted resources with an estimated monthly cost of %.2f\n", len(items), total)
+
+	if err := writeToJsonFile(items, out); err != nil {
+		log.Println("Error writing waste report:", err)
+	}
+}
+
+// listElasticIPs retrieves the Elastic IP addresses and their association
+func listElasticIPs(cfg aws.Config) ([]ResourceMetadata, error) {
+	svc := ec2.NewFromConfig(cfg)
+	result, err := svc.DescribeAddresses(context.TODO(), &ec2.DescribeAddressesInput{})
+	if err != nil {
+		return nil, err
+	}
+
+	var resources []ResourceMetadata
+	for _, address := range result.Addresses {
+		metadata := map[string]interface{}{
+			"public_ip": aws.ToString(address.PublicIp),
+			"domain":    address.Domain,
+			"tags":      getTags(address.Tags),
+		}
+		if address.AssociationId != nil {
+			metadata["association_id"] = *address.AssociationId
+		}
+		if address.InstanceId != nil {
+			metadata["instance_id"] = *address.InstanceId
+		}
+		if address.NetworkInterfaceId != nil {
+			metadata["network_interface_id"] = *address.NetworkInterfaceId
+		}
+
+		resources = append(resources, ResourceMetadata{
+			ResourceID: aws.ToString(address.AllocationId),
+			MetaData:   metadata,
+		})
+	}
+
+	return resources, nil
+}
+
+func findUnattachedVolumes(volumes []ResourceMetadata) []WasteItem {
+	var items []WasteItem
+	for _, volume := range volumes {
+		if metadataString(volume.MetaData, "state") == "available" {
+			items = append(items, WasteItem{
+				ServiceName: "Amazon Elastic Block Store",
+				ResourceID:  volume.ResourceID,
+				Reason:      "volume is not attached to any instance",
+				MetaData:    volume.MetaData,
+			})
+		}
+	}
+	return items
+}
+
+func findStoppedInstancesWithStorage(instances []ResourceMetadata) []WasteItem {
+	var items []WasteItem
+	for _, instance := range instances {
+		if metadataString(instance.MetaData, "state") != "stopped" {
+			continue
+		}
+		if metadataLen(instance.MetaData, "volumes") == 0 {
+			continue
+		}
+		items = append(items, WasteItem{
+			ServiceName: "Amazon Elastic Compute Cloud - Compute",
+			ResourceID:  instance.ResourceID,
+			Reason:      "instance is stopped but still has attached volumes",
+			MetaData:    instance.MetaData,
+		})
+	}
+	return items
+}
+
+func findLoadBalancersWithoutHealthyTargets(elbs []ResourceMetadata) []WasteItem {
+	var items []WasteItem
+	for _, elb := range elbs {
+		// Targets that could not be described leave healthy_targets unset
+		if _, ok := elb.MetaData["healthy_targets"]; !ok {
+			continue
+		}
+		// Load balancers without any target group have no healthy targets either
+		if metadataInt(elb.MetaData, "healthy_targets") == 0 {
+			items = append(items, WasteItem{
+				ServiceName: "Amazon Elastic Load Balancing",
+				ResourceID:  elb.ResourceID,
+				Reason:      "load balancer has no healthy targets",
+				MetaData:    elb.MetaData,
+			})
+		}
+	}
+	return items
+}
+
+func findUnassociatedElasticIPs(addresses []ResourceMetadata) []WasteItem {
+	var items []WasteItem
+	for _, address := range addresses {
+		if _, ok := address.MetaData["association_id"]; !ok {
+			items = append(items, WasteItem{
+				ServiceName: "Amazon Virtual Private Cloud",
+				ResourceID:  address.ResourceID,
+				Reason:      "elastic IP is not associated with any resource",
+				MetaData:    address.MetaData,
+			})
+		}
+	}
+	return items
+}
+
+func findIdleRDSInstances(cfg aws.Config, dbInstances []ResourceMetadata) []WasteItem {
+	svc := cloudwatch.NewFromConfig(cfg)
+
+	var items []WasteItem
+	for _, db := range dbInstances {
+		if metadataString(db.MetaData, "status") != "available" {
+			continue
+		}
+		connections, ok, err := getRDSMaxConnections(svc, db.ResourceID, rdsIdleDays)
+		if err != nil {
+			log.Printf("Error getting connections for RDS instance %s: %v\n", db.ResourceID, err)
+			continue
+		}
+		// Without datapoints the connections are unknown, not zero
+		if !ok || connections > 0 {
+			continue
+		}
+		items = append(items, WasteItem{
+			ServiceName: "Amazon Relational Database Service",
+			ResourceID:  db.ResourceID,
+			Reason:      fmt.Sprintf("database had no connections in the last %d days", rdsIdleDays),
+			MetaData:    db.MetaData,
+		})
+	}
+	return items
+}
+
+// getRDSMaxConnections returns the highest number of database connections seen over the last days, and whether
+// CloudWatch had any datapoints
+func getRDSMaxConnections(svc *cloudwatch.Client, dbInstanceID string, days int) (float64, bool, error) {
+	endTime := time.Now()
+	startTime := endTime.AddDate(0, 0, -days)
+
+	result, err := svc.GetMetricStatistics(context.TODO(), &cloudwatch.GetMetricStatisticsInput{
+		Namespace:  aws.String("AWS/RDS"),
+		MetricName: aws.String("DatabaseConnections"),
+		Dimensions: []cwTypes.Dimension{{
+			Name:  aws.String("DBInstanceIdentifier"),
+			Value: aws.String(dbInstanceID),
+		}},
+		StartTime:  aws.Time(startTime),
+		EndTime:    aws.Time(endTime),
+		Period:     aws.Int32(86400),
+		Statistics: []cwTypes.Statistic{cwTypes.StatisticMaximum},
+	})
+	if err != nil {
+		return 0, false, err
+	}
+
+	var max float64
+	found := false
+	for _, datapoint := range result.Datapoints {
+		if datapoint.Maximum != nil {
+			found = true
+			if *datapoint.Maximum > max {
+				max = *datapoint.Maximum
+			}
+		}
+	}
+	return max, found, nil
+}
+
+// getResourceMonthlyCosts returns the cost per resource ID projected to 30 days.
+// Cost Explorer only keeps resource level data for the last 14 days.
+func getResourceMonthlyCosts(cfg aws.Config) (map[string]float64, error) {
+	svc := costexplorer.NewFromConfig(cfg)
+
+	days := 14
+	endTime := time.Now()
+	startTime := endTime.AddDate(0, 0, -days)
+
+	input := &costexplorer.GetCostAndUsageWithResourcesInput{
+		TimePeriod: &costTypes.DateInterval{
+			Start: aws.String(startTime.Format("2006-01-02")),
+			End:   aws.String(endTime.Format("2006-01-02")),
+		},
+		Granularity: costTypes.GranularityDaily,
+		Metrics:     []string{"UnblendedCost"},
+		Filter: &costTypes.Expression{
+			Dimensions: &costTypes.DimensionValues{
+				Key: costTypes.DimensionService,
+				Values: []string{
+					"Amazon Elastic Compute Cloud - Compute",
+					"EC2 - Other",
+					"Amazon Elastic Load Balancing",
+					"Amazon Relational Database Service",
+					"Amazon Virtual Private Cloud",
+				},
+			},
+		},
+		GroupBy: []costTypes.GroupDefinition{
+			{
+				Type: costTypes.GroupDefinitionTypeDimension,
+				Key:  aws.String("RESOURCE_ID"),
+			},
+		},
+	}
+
+	costs := make(map[string]float64)
+	for {
+		resp, err := svc.GetCostAndUsageWithResources(context.TODO(), input)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, result := range resp.ResultsByTime {
+			for _, group := range result.Groups {
+				metric, ok := group.Metrics["UnblendedCost"]
+				if !ok || metric.Amount == nil || len(group.Keys) == 0 {
+					continue
+				}
+				amount, err := strconv.ParseFloat(*metric.Amount, 64)
+				if err != nil {
+					continue
+				}
+				costs[group.Keys[0]] += amount
+			}
+		}
+
+		if resp.NextPageToken == nil {
+			break
+		}
+		input.NextPageToken = resp.NextPageToken
+	}
+
+	for id, cost := range costs {
+		costs[id] = cost / float64(days) * 30
+	}
+	return costs, nil
+}
+
+// applyWasteCosts sets the estimated monthly cost of each item from the per resource costs.
+// Stopped instances are only charged for their volumes, so those are added up instead of the instance's own
+// cost, which is compute from before it stopped.
+func applyWasteCosts(items []WasteItem, costs map[string]float64) {
+	if len(costs) == 0 {
+		return
+	}
+
+	for i := range items {
+		var total float64
+		found := false
+
+		ids := metadataVolumeIDs(items[i].MetaData)
+		if len(ids) == 0 {
+			ids = []string{items[i].ResourceID}
+		}
+		for _, id := range ids {
+			if cost, ok := lookupResourceCost(costs, id); ok {
+				total += cost
+				found = true
+			}
+		}
+
+		if found {
+			items[i].EstimatedMonthlyCost = aws.Float64(total)
+		}
+	}
+}
+
+// lookupResourceCost matches a resource ID against Cost Explorer IDs, which are ARNs for some services. An ARN
+// matches when its last segment is the resource ID, and only when no other ARN does.
+func lookupResourceCost(costs map[string]float64, resourceID string) (float64, bool) {
+	if cost, ok := costs[resourceID]; ok {
+		return cost, true
+	}
+	var match float64
+	matches := 0
+	for id, cost := range costs {
+		if strings.HasPrefix(id, "arn:") && lastARNSegment(id) == resourceID {
+			match = cost
+			matches++
+		}
+	}
+	return match, matches == 1
+}
+
+func metadataVolumeIDs(metadata map[string]interface{}) []string {
+	var ids []string
+	switch volumes := metadata["volumes"].(type) {
+	case []map[string]interface{}:
+		for _, volume := range volumes {
+			ids = append(ids, metadataString(volume, "volume_id"))
+		}
+	case []interface{}:
+		for _, volume := range volumes {
+			if v, ok := volume.(map[string]interface{}); ok {
+				ids = append(ids, metadataString(v, "volume_id"))
+			}
+		}
+	}
+	return ids
+}
+
+// metadataString returns a metadata value as a string, whether it was collected or loaded from a file
+func metadataString(metadata map[string]interface{}, key string) string {
+	value, ok := metadata[key]
+	if !ok || value == nil {
+		return ""
+	}
+	if s, ok := value.(*string); ok {
+		return aws.ToString(s)
+	}
+	return fmt.Sprint(value)
+}
+
+func metadataInt(metadata map[string]interface{}, key string) int {
+	switch value := metadata[key].(type) {
+	case int:
+		return value
+	case int32:
+		return int(value)
+	case int64:
+		return int(value)
+	case float64:
+		return int(value)
+	}
+	return 0
+}
+
+func metadataLen(metadata map[string]interface{}, key string) int {
+	switch value := metadata[key].(type) {
+	case []map[string]interface{}:
+		return len(value)
+	case []interface{}:
+		return len(value)
+	case []string:
+		return len(value)
+	}
+	return 0
+}
@@ -0,0 +1,84 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindWaste(t *testing.T) {
+	volumes := []ResourceMetadata{
+		{ResourceID: "vol-1", MetaData: map[string]interface{}{"state": "available"}},
+		{ResourceID: "vol-2", MetaData: map[string]interface{}{"state": "in-use"}},
+	}
+	items := findUnattachedVolumes(volumes)
+	assert.Len(t, items, 1)
+	assert.Equal(t, "vol-1", items[0].ResourceID)
+
+	instances := []ResourceMetadata{
+		{ResourceID: "i-1", MetaData: map[string]interface{}{
+			"state":   "stopped",
+			"volumes": []map[string]interface{}{{"volume_id": "vol-3"}},
+		}},
+		{ResourceID: "i-2", MetaData: map[string]interface{}{"state": "stopped"}},
+		{ResourceID: "i-3", MetaData: map[string]interface{}{
+			"state":   "running",
+			"volumes": []map[string]interface{}{{"volume_id": "vol-4"}},
+		}},
+	}
+	items = findStoppedInstancesWithStorage(instances)
+	assert.Len(t, items, 1)
+	assert.Equal(t, "i-1", items[0].ResourceID)
+
+	elbs := []ResourceMetadata{
+		{ResourceID: "arn:elb-1", MetaData: map[string]interface{}{"healthy_targets": 0}},
+		{ResourceID: "arn:elb-2", MetaData: map[string]interface{}{"healthy_targets": 2}},
+		// Loaded from an inventory file
+		{ResourceID: "arn:elb-3", MetaData: map[string]interface{}{"healthy_targets": float64(1)}},
+		// Targets could not be described
+		{ResourceID: "arn:elb-4", MetaData: map[string]interface{}{}},
+	}
+	items = findLoadBalancersWithoutHealthyTargets(elbs)
+	assert.Len(t, items, 1)
+	assert.Equal(t, "arn:elb-1", items[0].ResourceID)
+
+	addresses := []ResourceMetadata{
+		{ResourceID: "eipalloc-1", MetaData: map[string]interface{}{}},
+		{ResourceID: "eipalloc-2", MetaData: map[string]interface{}{"association_id": "eipassoc-2"}},
+	}
+	items = findUnassociatedElasticIPs(addresses)
+	assert.Len(t, items, 1)
+	assert.Equal(t, "eipalloc-1", items[0].ResourceID)
+}
+
+func TestApplyWasteCosts(t *testing.T) {
+	items := []WasteItem{
+		{ResourceID: "vol-1", MetaData: map[string]interface{}{}},
+		{ResourceID: "i-1", MetaData: map[string]interface{}{
+			"volumes": []map[string]interface{}{{"volume_id": "vol-2"}, {"volume_id": "vol-3"}},
+		}},
+		{ResourceID: "db-1", MetaData: map[string]interface{}{}},
+		{ResourceID: "eipalloc-1", MetaData: map[string]interface{}{}},
+		{ResourceID: "web", MetaData: map[string]interface{}{}},
+	}
+	costs := map[string]float64{
+		"vol-1": 10,
+		"vol-2": 5,
+		"vol-3": 2.5,
+		// Compute from before the instance stopped
+		"i-1": 40,
+		"arn:aws:rds:eu-west-1:123456789012:db:db-1":     100,
+		"arn:aws:rds:eu-west-1:123456789012:db:app-db-1": 50,
+		// Two ARNs end in web, neither is picked
+		"arn:aws:rds:eu-west-1:123456789012:db:web":      30,
+		"arn:aws:rds:eu-west-1:123456789012:cluster:web": 60,
+	}
+
+	applyWasteCosts(items, costs)
+
+	assert.Equal(t, 10.0, *items[0].EstimatedMonthlyCost)
+	assert.Equal(t, 7.5, *items[1].EstimatedMonthlyCost)
+	assert.Equal(t, 100.0, *items[2].EstimatedMonthlyCost)
+	assert.Nil(t, items[3].EstimatedMonthlyCost)
+	assert.Nil(t, items[4].EstimatedMonthlyCost)
+}
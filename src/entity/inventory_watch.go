@@ -0,0 +1,273 @@
+package entity
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/spf13/cobra"
+)
+
+// Changes listed in a Slack message, the rest are summarized in the counts
+const slackChangeLimit = 20
+
+// WatchEvent is posted to the webhook after a collection that found resource changes
+type WatchEvent struct {
+	CollectedAt time.Time        `json:"collected_at"`
+	Changes     []ResourceChange `json:"changes"`
+}
+
+func watchInventoryCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "watch",
+		Short: "Collect the inventory on an interval and post resource changes to a webhook",
+		Run: func(cmd *cobra.Command, args []string) {
+			webhook, _ := cmd.Flags().GetString("webhook")
+			format, _ := cmd.Flags().GetString("format")
+			interval, _ := cmd.Flags().GetDuration("interval")
+			history, _ := cmd.Flags().GetString("history")
+			discover, _ := cmd.Flags().GetString("discover")
+			window, _ := cmd.Flags().GetDuration("window")
+			stackMembership, _ := cmd.Flags().GetBool("stack-membership")
+			if format != "json" && format != "slack" {
+				log.Printf("Unknown webhook format %s, use json or slack\n", format)
+				os.Exit(exitFailure)
+			}
+			if discover != "cost" && discover != "cloudtrail" {
+				log.Printf("Unknown discovery source %s, use cost or cloudtrail\n", discover)
+				os.Exit(exitFailure)
+			}
+			if interval <= 0 {
+				log.Printf("Invalid interval %s, use a positive duration like 15m\n", interval)
+				os.Exit(exitFailure)
+			}
+			log.Printf("Watching inventory every %s\n", interval)
+			err := watchInventory(webhook, format, interval, inventoryOptions{
+				Discover:        discover,
+				Window:          window,
+				History:         history,
+				AWS:             awsConfigOptionsFromFlags(cmd),
+				StackMembership: stackMembership,
+			})
+			if err != nil {
+				log.Printf("Error watching inventory: %v\n", err)
+				os.Exit(exitFailure)
+			}
+		},
+	}
+	cmd.Flags().String("webhook", "", "URL the changes are posted to")
+	cmd.Flags().String("format", "json", "Webhook payload: json, or slack for a Slack incoming webhook")
+	cmd.Flags().Duration("interval", 15*time.Minute, "Time between two inventory collections")
+	cmd.Flags().String("history", "", "History directory to compare the first collection with and save each collection to")
+	cmd.Flags().String("discover", "cost", "How to find the services to collect: cost, or cloudtrail to add services with recent write events")
+	cmd.Flags().Duration("window", 7*24*time.Hour, "CloudTrail window used with --discover=cloudtrail")
+	cmd.Flags().Bool("stack-membership", false, "Record the CloudFormation stack of each resource, even when CloudFormation is not a top service")
+	addAWSConfigFlags(cmd)
+
+	cmd.MarkFlagRequired("webhook")
+	cmd.MarkFlagDirname("history")
+	return cmd
+}
+
+func watchInventory(webhook, format string, interval time.Duration, options inventoryOptions) error {
+	cfg, err := loadAWSConfig(options.AWS)
+	if err != nil {
+		return err
+	}
+
+	// Without history the first collection is the baseline and posts nothing
+	var previous []ServiceMetadata
+	if options.History != "" {
+		index, err := readSnapshotIndex(options.History)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		if len(index) > 0 {
+			previous, err = readSnapshot(options.History, index[len(index)-1])
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for {
+		previous = watchCollect(cfg, webhook, format, previous, options)
+		time.Sleep(interval)
+	}
+}
+
+// watchCollect collects the inventory, posts its changes from the previous one and returns the inventory to
+// compare the next collection with
+func watchCollect(cfg aws.Config, webhook, format string, previous []ServiceMetadata, options inventoryOptions) []ServiceMetadata {
+	start := time.Now()
+	services, _, errs, err := collectInventory(cfg, options)
+	if err != nil {
+		log.Println("Error collecting inventory:", err)
+		return previous
+	}
+
+	// The history records what was collected, what is carried over only hides failures from the comparison
+	if options.History != "" {
+		if err := saveSnapshot(options.History, services, start); err != nil {
+			log.Println("Error saving inventory snapshot:", err)
+		}
+	}
+	services = carryOverFailedSteps(previous, services, errs)
+	if previous == nil {
+		log.Printf("Collected %d services as the baseline\n", len(services))
+		return services
+	}
+
+	changes := diffInventories(previous, services)
+	log.Printf("Found %d resource changes\n", len(changes))
+	if len(changes) > 0 {
+		if err := postWatchEvent(webhook, format, WatchEvent{CollectedAt: start, Changes: changes}); err != nil {
+			log.Println("Error posting changes:", err)
+		}
+	}
+	return services
+}
+
+// Resource metadata keys set by the collection steps that link resources, keyed by step
+var watchLinkSteps = map[string]string{
+	"cloudformation_stacks": "cloudformation_stack",
+	"cloudwatch_alarms":     "alarms",
+}
+
+// carryOverFailedSteps keeps what the previous collection found for every service or step that failed this time,
+// so a denied or throttled call is not reported as resources disappearing or changing. Services missing from the
+// collection keep their previous resources only when their collector or the service list failed, a service Cost
+// Explorer no longer lists disappears.
+func carryOverFailedSteps(previous, services []ServiceMetadata, errs []InventoryError) []ServiceMetadata {
+	failedSteps := make(map[string]bool)
+	failedServices := make(map[string]bool)
+	for _, e := range errs {
+		failedSteps[e.Step] = true
+		if e.ServiceName != "" {
+			failedServices[e.ServiceName] = true
+		}
+	}
+
+	collected := make(map[string]int)
+	for i, service := range services {
+		collected[service.ServiceName] = i
+	}
+	for _, service := range previous {
+		i, ok := collected[service.ServiceName]
+		switch {
+		case !ok && (failedServices[service.ServiceName] || failedSteps["service_costs"]):
+			collected[service.ServiceName] = len(services)
+			services = append(services, service)
+		case !ok:
+			continue
+		case failedServices[service.ServiceName]:
+			// A step of the collector failed, e.g. the Aurora clusters listed with RDS
+			services[i].Resources = appendMissingResources(services[i].Resources, service.Resources, "")
+		case failedSteps["cloudwatch_alarms"]:
+			services[i].Resources = appendMissingResources(services[i].Resources, service.Resources, "alarm")
+		}
+	}
+
+	for step, key := range watchLinkSteps {
+		if !failedSteps[step] {
+			continue
+		}
+		before := make(map[string]map[string]interface{})
+		for _, service := range previous {
+			for _, resource := range service.Resources {
+				before[service.ServiceName+"/"+resource.ResourceID] = resource.MetaData
+			}
+		}
+		for _, service := range services {
+			for _, resource := range service.Resources {
+				metadata, ok := before[service.ServiceName+"/"+resource.ResourceID]
+				if !ok || resource.MetaData == nil {
+					continue
+				}
+				if value, ok := metadata[key]; ok {
+					resource.MetaData[key] = value
+				} else {
+					delete(resource.MetaData, key)
+				}
+			}
+		}
+	}
+	return services
+}
+
+// appendMissingResources appends the previous resources that are not in resources, only those of a resource type
+// when one is given
+func appendMissingResources(resources, previous []ResourceMetadata, resourceType string) []ResourceMetadata {
+	found := make(map[string]bool)
+	for _, resource := range resources {
+		found[resource.ResourceID] = true
+	}
+	for _, resource := range previous {
+		if found[resource.ResourceID] || (resourceType != "" && metadataString(resource.MetaData, "resource_type") != resourceType) {
+			continue
+		}
+		resources = append(resources, resource)
+	}
+	return resources
+}
+
+// postWatchEvent posts the event as JSON, or as a Slack message summarizing the changes
+func postWatchEvent(webhook, format string, event WatchEvent) error {
+	var payload interface{} = event
+	if format == "slack" {
+		payload = map[string]string{"text": slackChangeText(event)}
+	}
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	req, err := http.NewRequest("POST", webhook, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Webhooks answer 200, 202 or 204
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("received non-OK HTTP status: %s", resp.Status)
+	}
+	return nil
+}
+
+func slackChangeText(event WatchEvent) string {
+	counts := make(map[string]int)
+	for _, change := range event.Changes {
+		counts[change.Change]++
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Inventory changed at %s: %d appeared, %d changed, %d disappeared\n", event.CollectedAt.UTC().Format(time.RFC3339),
+		counts["appeared"], counts["changed"], counts["disappeared"])
+	for i, change := range event.Changes {
+		if i == slackChangeLimit {
+			fmt.Fprintf(&b, "… and %d more\n", len(event.Changes)-slackChangeLimit)
+			break
+		}
+		fmt.Fprintf(&b, "• %s %s `%s`", change.Change, change.ServiceName, change.ResourceID)
+		if len(change.Keys) > 0 {
+			fmt.Fprintf(&b, " (%s)", strings.Join(change.Keys, ", "))
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,78 @@
+package entity
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatchCollectionChanges(t *testing.T) {
+	previous := []ServiceMetadata{
+		{ServiceName: "Amazon Simple Storage Service", Resources: []ResourceMetadata{{ResourceID: "logs"}}},
+		{ServiceName: "Amazon Elastic Compute Cloud - Compute", Resources: []ResourceMetadata{
+			{ResourceID: "i-1", MetaData: map[string]interface{}{"instance_type": "t3.micro"}},
+		}},
+	}
+	// S3 was denied, its bucket must not be reported as disappeared
+	services := carryOverFailedSteps(previous, []ServiceMetadata{
+		{ServiceName: "Amazon Elastic Compute Cloud - Compute", Resources: []ResourceMetadata{
+			{ResourceID: "i-1", MetaData: map[string]interface{}{"instance_type": "t3.large"}},
+			{ResourceID: "i-2"},
+		}},
+	}, []InventoryError{{ServiceName: "Amazon Simple Storage Service", ServiceCode: "s3", Step: "list_resources", Kind: "auth"}})
+
+	changes := diffInventories(previous, services)
+	assert.Equal(t, []ResourceChange{
+		{ServiceName: "Amazon Elastic Compute Cloud - Compute", ResourceID: "i-1", Change: "changed", Keys: []string{"instance_type"}},
+		{ServiceName: "Amazon Elastic Compute Cloud - Compute", ResourceID: "i-2", Change: "appeared"},
+	}, changes)
+
+	// Alarms, stacks and RDS clusters failed, Lambda fell out of the Cost Explorer result and disappears
+	previous = []ServiceMetadata{
+		{ServiceName: "AmazonCloudWatch", Resources: []ResourceMetadata{
+			{ResourceID: "high-cpu", MetaData: map[string]interface{}{"resource_type": "alarm"}},
+			{ResourceID: "/aws/lambda/handler", MetaData: map[string]interface{}{"resource_type": "log_group"}},
+		}},
+		{ServiceName: "Amazon Relational Database Service", Resources: []ResourceMetadata{
+			{ResourceID: "orders-1", MetaData: map[string]interface{}{"alarms": []string{"high-cpu"}, "cloudformation_stack": "orders"}},
+			{ResourceID: "orders", MetaData: map[string]interface{}{"resource_type": "cluster"}},
+		}},
+		{ServiceName: "AWS Lambda", Resources: []ResourceMetadata{{ResourceID: "handler"}}},
+	}
+	services = carryOverFailedSteps(previous, []ServiceMetadata{
+		{ServiceName: "AmazonCloudWatch", Resources: []ResourceMetadata{
+			{ResourceID: "/aws/lambda/handler", MetaData: map[string]interface{}{"resource_type": "log_group"}},
+		}},
+		{ServiceName: "Amazon Relational Database Service", Resources: []ResourceMetadata{
+			{ResourceID: "orders-1", MetaData: map[string]interface{}{}},
+		}},
+	}, []InventoryError{
+		{ServiceName: "Amazon Relational Database Service", ServiceCode: "rds", Step: "rds_clusters"},
+		{ServiceCode: "cloudformation", Step: "cloudformation_stacks"},
+		{ServiceCode: "cloudwatch", Step: "cloudwatch_alarms"},
+	})
+	assert.Equal(t, []ResourceChange{
+		{ServiceName: "AWS Lambda", ResourceID: "handler", Change: "disappeared"},
+	}, diffInventories(previous, services))
+
+	// Without the service list every service missing from the collection is kept
+	services = carryOverFailedSteps(previous, []ServiceMetadata{}, []InventoryError{{Step: "service_costs"}})
+	assert.Empty(t, diffInventories(previous, services))
+
+	var payload map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	err := postWatchEvent(server.URL, "slack", WatchEvent{CollectedAt: time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC), Changes: changes})
+	assert.NoError(t, err)
+	assert.Equal(t, "Inventory changed at 2026-10-01T12:00:00Z: 1 appeared, 1 changed, 0 disappeared\n"+
+		"• changed Amazon Elastic Compute Cloud - Compute `i-1` (instance_type)\n"+
+		"• appeared Amazon Elastic Compute Cloud - Compute `i-2`\n", payload["text"])
+}
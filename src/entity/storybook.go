@@ -21,6 +21,7 @@ func StorybookCommand() *cobra.Command {
 
 	cmd.AddCommand(makeStorybooksCommand())
 	cmd.AddCommand(saveStorybooksCommand())
+	cmd.AddCommand(coverageStorybooksCommand())
 	return cmd
 }
 
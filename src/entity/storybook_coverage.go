@@ -0,0 +1,206 @@
+package entity
+
+import (
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// Namespace selectors of PQL what clauses, e.g. Namespace='AWS/EC2' or Namespace='AWS/*'.
+// The value also ends at the next selector, for selectors missing their closing quote.
+var pqlNamespaceSelector = regexp.MustCompile(`Namespace='([^';"]*)`)
+
+// StorybookCoverageReport shows which inventoried services no PQL queries, and which PQLs query namespaces
+// without resources
+type StorybookCoverageReport struct {
+	Services            []ServiceCoverage `json:"services"`
+	UncoveredNamespaces []string          `json:"uncovered_namespaces"`
+	StalePQLs           []StalePQL        `json:"stale_pqls"`
+}
+
+// ServiceCoverage lists the PQLs querying the namespaces of a service's resources, none when it is uncovered
+type ServiceCoverage struct {
+	ServiceName   string   `json:"service_name"`
+	Namespaces    []string `json:"namespaces"`
+	ResourceCount int      `json:"resource_count"`
+	PQLs          []string `json:"pqls"`
+}
+
+// StalePQL is a PQL with namespace selectors matching no inventoried resource
+type StalePQL struct {
+	File       string   `json:"file"`
+	Namespaces []string `json:"namespaces"`
+}
+
+func coverageStorybooksCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "coverage",
+		Short: "Show inventoried services without PQLs and PQLs querying namespaces without resources",
+		Run: func(cmd *cobra.Command, args []string) {
+			inventory, _ := cmd.Flags().GetString("inventory")
+			in, _ := cmd.Flags().GetString("in")
+			out, _ := cmd.Flags().GetString("out")
+			err := makeStorybookCoverage(inventory, in, out)
+			if err != nil {
+				log.Printf("Error creating storybook coverage: %v\n", err)
+			}
+		},
+	}
+	cmd.Flags().String("inventory", "", "Input path of the inventory file")
+	cmd.Flags().String("in", "", "Storybooks directory, every .pql file below it is read")
+	cmd.Flags().String("out", "", "Output path for the coverage report, only printed when omitted")
+
+	cmd.MarkFlagRequired("inventory")
+	cmd.MarkFlagRequired("in")
+	cmd.MarkFlagFilename("inventory")
+	cmd.MarkFlagDirname("in")
+	cmd.MarkFlagFilename("out")
+	return cmd
+}
+
+func makeStorybookCoverage(inventory, in, out string) error {
+	services, err := readInventoryFile(inventory)
+	if err != nil {
+		return err
+	}
+	pqls, err := readPQLNamespaces(in)
+	if err != nil {
+		return err
+	}
+
+	report := storybookCoverage(services, pqls)
+	for _, service := range report.Services {
+		if len(service.PQLs) == 0 {
+			fmt.Printf("uncovered  %s (%d resources in %s)\n", service.ServiceName, service.ResourceCount,
+				strings.Join(service.Namespaces, ", "))
+		}
+	}
+	for _, namespace := range report.UncoveredNamespaces {
+		fmt.Printf("uncovered  namespace %s\n", namespace)
+	}
+	for _, pql := range report.StalePQLs {
+		fmt.Printf("stale      %s (%s)\n", pql.File, strings.Join(pql.Namespaces, ", "))
+	}
+
+	if out != "" {
+		return writeToJsonFile(report, out)
+	}
+	return nil
+}
+
+// readPQLNamespaces returns the namespace selectors of every .pql file below dir, keyed by the path relative to dir
+func readPQLNamespaces(dir string) (map[string][]string, error) {
+	pqls := make(map[string][]string)
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() || filepath.Ext(path) != ".pql" {
+			return err
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		name, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		pqls[name] = parsePQLNamespaces(string(content))
+		return nil
+	})
+	return pqls, err
+}
+
+// parsePQLNamespaces returns the distinct namespace selectors of a PQL, skipping commented out lines.
+// A selector may hold several patterns separated by |.
+func parsePQLNamespaces(content string) []string {
+	namespaces := make(map[string]bool)
+	for _, line := range strings.Split(content, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "//") {
+			continue
+		}
+		for _, match := range pqlNamespaceSelector.FindAllStringSubmatch(line, -1) {
+			for _, pattern := range strings.Split(match[1], "|") {
+				namespaces[pattern] = true
+			}
+		}
+	}
+	return sortedKeys(namespaces)
+}
+
+// storybookCoverage matches the namespace selectors of the PQLs with the CloudWatch namespaces of the
+// inventory resources
+func storybookCoverage(services []ServiceMetadata, pqls map[string][]string) StorybookCoverageReport {
+	inventoried := make(map[string]bool)
+	for _, service := range services {
+		for _, resource := range service.Resources {
+			if namespace := metadataString(resource.MetaData, "cloudwatch_namespace"); namespace != "" {
+				inventoried[namespace] = true
+			}
+		}
+	}
+
+	// PQLs querying each inventoried namespace
+	queriedBy := make(map[string][]string)
+	report := StorybookCoverageReport{UncoveredNamespaces: []string{}, StalePQLs: []StalePQL{}}
+	for _, file := range sortedKeys(pqls) {
+		var stale []string
+		for _, pattern := range pqls[file] {
+			matched := false
+			for namespace := range inventoried {
+				if matchSelectorPattern(pattern, namespace) {
+					queriedBy[namespace] = append(queriedBy[namespace], file)
+					matched = true
+				}
+			}
+			if !matched {
+				stale = append(stale, pattern)
+			}
+		}
+		if len(stale) > 0 {
+			report.StalePQLs = append(report.StalePQLs, StalePQL{File: file, Namespaces: stale})
+		}
+	}
+
+	for _, namespace := range sortedKeys(inventoried) {
+		if len(queriedBy[namespace]) == 0 {
+			report.UncoveredNamespaces = append(report.UncoveredNamespaces, namespace)
+		}
+	}
+
+	for _, service := range services {
+		if len(service.Resources) == 0 {
+			continue
+		}
+		coverage := ServiceCoverage{ServiceName: service.ServiceName, ResourceCount: len(service.Resources), Namespaces: []string{}}
+		namespaces := make(map[string]bool)
+		files := make(map[string]bool)
+		for _, resource := range service.Resources {
+			if namespace := metadataString(resource.MetaData, "cloudwatch_namespace"); namespace != "" {
+				namespaces[namespace] = true
+				for _, file := range queriedBy[namespace] {
+					files[file] = true
+				}
+			}
+		}
+		coverage.Namespaces = append(coverage.Namespaces, sortedKeys(namespaces)...)
+		coverage.PQLs = append([]string{}, sortedKeys(files)...)
+		report.Services = append(report.Services, coverage)
+	}
+	sort.SliceStable(report.Services, func(i, j int) bool {
+		return report.Services[i].ServiceName < report.Services[j].ServiceName
+	})
+	return report
+}
+
+// matchSelectorPattern matches a selector value, where * matches any text including /
+func matchSelectorPattern(pattern, value string) bool {
+	expression := "^" + strings.ReplaceAll(regexp.QuoteMeta(pattern), `\*`, ".*") + "$"
+	matched, _ := regexp.MatchString(expression, value)
+	return matched
+}
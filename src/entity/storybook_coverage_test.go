@@ -0,0 +1,45 @@
+package entity
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStorybookCoverage(t *testing.T) {
+	pqls, err := readPQLNamespaces(filepath.Join("..", "..", "templates", "storybooks"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"AWS/ApiGateway"}, pqls[filepath.Join("pqls", "aws-apigateway-slo.pql")])
+	assert.Equal(t, []string{"AWS/RDS"}, pqls[filepath.Join("pqls", "aws-rds-utilization.pql")])
+
+	assert.Equal(t, []string{"AWS/*", "Custom/Orders"}, parsePQLNamespaces(
+		"where(@cw_aws)\n//.what(\"Namespace='AWS/Old'\")\n.what(\"MetricName='Errors';Namespace='Custom/Orders|AWS/*'\")"))
+
+	services := []ServiceMetadata{
+		{ServiceName: "Amazon Elastic Compute Cloud - Compute", Resources: []ResourceMetadata{
+			{ResourceID: "i-1", MetaData: map[string]interface{}{"cloudwatch_namespace": "AWS/EC2"}},
+		}},
+		{ServiceName: "Amazon Simple Queue Service", Resources: []ResourceMetadata{
+			{ResourceID: "orders", MetaData: map[string]interface{}{"cloudwatch_namespace": "AWS/SQS"}},
+		}},
+	}
+	report := storybookCoverage(services, map[string][]string{
+		"ec2.pql":    {"AWS/EC2"},
+		"legacy.pql": {"AWS/EC2", "AWS/ELB"},
+	})
+	assert.Equal(t, []ServiceCoverage{
+		{ServiceName: "Amazon Elastic Compute Cloud - Compute", Namespaces: []string{"AWS/EC2"}, ResourceCount: 1, PQLs: []string{"ec2.pql", "legacy.pql"}},
+		{ServiceName: "Amazon Simple Queue Service", Namespaces: []string{"AWS/SQS"}, ResourceCount: 1, PQLs: []string{}},
+	}, report.Services)
+	assert.Equal(t, []string{"AWS/SQS"}, report.UncoveredNamespaces)
+	assert.Equal(t, []StalePQL{{File: "legacy.pql", Namespaces: []string{"AWS/ELB"}}}, report.StalePQLs)
+
+	out := filepath.Join(t.TempDir(), "coverage.json")
+	inventory := filepath.Join(t.TempDir(), "inventory.json")
+	assert.NoError(t, writeToJsonFile(services, inventory))
+	assert.NoError(t, makeStorybookCoverage(inventory, filepath.Join("..", "..", "templates", "storybooks"), out))
+	_, err = os.Stat(out)
+	assert.NoError(t, err)
+}
@@ -39,6 +39,9 @@ func UICommand() *cobra.Command {
 	cmd.AddCommand(getUiChatCommand())
 	cmd.AddCommand(saveUiChatCommand())
 
+	//Knowledge
+	cmd.AddCommand(saveUiKnowledgeCommand())
+
 	return cmd
 }
 
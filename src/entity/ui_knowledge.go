@@ -0,0 +1,324 @@
+package entity
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"mime/multipart"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// Prefix of the documents rendered from the inventory, documents with it are replaced on every upload
+const inventoryDocumentPrefix = "inventory-"
+
+var nonSlugCharacters = regexp.MustCompile(`[^a-z0-9]+`)
+
+// Knowledge is an Open WebUI knowledge collection
+type Knowledge struct {
+	ID          string          `json:"id"`
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Files       []KnowledgeFile `json:"files"`
+}
+
+// KnowledgeFile is a document uploaded to Open WebUI
+type KnowledgeFile struct {
+	ID   string `json:"id"`
+	Meta struct {
+		Name string `json:"name"`
+	} `json:"meta"`
+}
+
+func saveUiKnowledgeCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "save:knowledge",
+		Short: "Save the inventory as documents of a UI knowledge collection",
+		Run: func(cmd *cobra.Command, args []string) {
+			token, _ := cmd.Flags().GetString("token")
+			server, _ := cmd.Flags().GetString("server")
+			in, _ := cmd.Flags().GetString("from-inventory")
+			name, _ := cmd.Flags().GetString("name")
+			log.Printf("Saving inventory %s to knowledge %s on server %s\n", in, name, server)
+			err := saveInventoryKnowledge(server, token, in, name)
+			if err != nil {
+				log.Printf("Error saving knowledge: %v\n", err)
+			}
+		},
+	}
+	cmd.Flags().String("token", "", "API token")
+	cmd.Flags().String("server", "", "Server URL")
+	cmd.Flags().String("from-inventory", "", "Inventory file to render one Markdown document per service from")
+	cmd.Flags().String("name", "Inventory", "Name of the knowledge collection, created when it does not exist")
+
+	cmd.MarkFlagRequired("token")
+	cmd.MarkFlagRequired("server")
+	cmd.MarkFlagRequired("from-inventory")
+	cmd.MarkFlagFilename("from-inventory")
+	return cmd
+}
+
+// saveInventoryKnowledge renders each service of the inventory as a Markdown document and uploads them to the
+// knowledge collection, replacing the documents of the previous upload
+func saveInventoryKnowledge(server, token, in, name string) error {
+	services, err := readInventoryFile(in)
+	if err != nil {
+		return err
+	}
+
+	knowledge, err := findOrCreateKnowledge(server, token, name)
+	if err != nil {
+		return err
+	}
+
+	// Documents are uploaded before the previous ones are removed, so a failed upload keeps the previous document
+	// of its service. Open WebUI rejects adding a document with the content of one already in the collection, an
+	// unchanged document keeps the previous one as well.
+	documents := inventoryDocuments(services)
+	kept := make(map[string]bool)
+	failed := 0
+	for _, fileName := range sortedKeys(documents) {
+		var file KnowledgeFile
+		if err := uploadOpenWebUIFile(server, token, fileName, documents[fileName], &file); err != nil {
+			log.Printf("Error uploading %s: %v\n", fileName, err)
+			kept[fileName] = true
+			failed++
+			continue
+		}
+		endpoint := server + "/api/v1/knowledge/" + knowledge.ID + "/file/add"
+		if err := openWebUIRequest("POST", endpoint, token, map[string]string{"file_id": file.ID}, nil); err != nil {
+			if isDuplicateContentError(err) {
+				log.Printf("Keeping the previous %s, it is unchanged\n", fileName)
+			} else {
+				log.Printf("Keeping the previous %s, adding it failed: %v\n", fileName, err)
+				failed++
+			}
+			kept[fileName] = true
+			if err := openWebUIRequest("DELETE", server+"/api/v1/files/"+file.ID, token, nil, nil); err != nil {
+				log.Printf("Error deleting the upload of %s: %v\n", fileName, err)
+			}
+			continue
+		}
+		log.Printf("Successfully saved document: %s\n", fileName)
+	}
+
+	// Removing a document from the collection also deletes its file. Documents of services that are gone are
+	// removed along with the ones replaced.
+	for _, file := range knowledge.Files {
+		if !strings.HasPrefix(file.Meta.Name, inventoryDocumentPrefix) || kept[file.Meta.Name] {
+			continue
+		}
+		endpoint := server + "/api/v1/knowledge/" + knowledge.ID + "/file/remove"
+		if err := openWebUIRequest("POST", endpoint, token, map[string]string{"file_id": file.ID}, nil); err != nil {
+			log.Printf("Error removing %s: %v\n", file.Meta.Name, err)
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d documents failed to upload or remove", failed)
+	}
+	return nil
+}
+
+func findOrCreateKnowledge(server, token, name string) (Knowledge, error) {
+	var collections []Knowledge
+	if err := openWebUIRequest("GET", server+"/api/v1/knowledge/", token, nil, &collections); err != nil {
+		return Knowledge{}, err
+	}
+
+	var knowledge Knowledge
+	for _, collection := range collections {
+		if collection.Name == name {
+			// The list leaves out the files of each collection
+			err := openWebUIRequest("GET", server+"/api/v1/knowledge/"+collection.ID, token, nil, &knowledge)
+			return knowledge, err
+		}
+	}
+
+	create := map[string]string{"name": name, "description": "Resources of the autopticli inventory, one document per service"}
+	err := openWebUIRequest("POST", server+"/api/v1/knowledge/create", token, create, &knowledge)
+	return knowledge, err
+}
+
+// inventoryDocuments renders one Markdown document per service, keyed by file name
+func inventoryDocuments(services []ServiceMetadata) map[string]string {
+	documents := make(map[string]string)
+	for _, service := range services {
+		fileName := inventoryDocumentPrefix + slugify(service.ServiceName)
+		if region := metadataString(service.MetaData, "region"); region != "" {
+			fileName += "-" + region
+		}
+		documents[fileName+".md"] += renderServiceMarkdown(service)
+	}
+	return documents
+}
+
+// slugify lowercases a name and joins its words with dashes, "Amazon Simple Queue Service" is amazon-simple-queue-service
+func slugify(name string) string {
+	return strings.Trim(nonSlugCharacters.ReplaceAllString(strings.ToLower(name), "-"), "-")
+}
+
+// renderServiceMarkdown lists the resources of a service with their scalar metadata and tags. Nested metadata,
+// such as metric catalogs and record sets, is left out to keep the documents small enough to retrieve.
+func renderServiceMarkdown(service ServiceMetadata) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", service.ServiceName)
+	for _, key := range []string{"account_id", "region"} {
+		if value := metadataString(service.MetaData, key); value != "" {
+			fmt.Fprintf(&b, "- %s: %s\n", key, value)
+		}
+	}
+	if cost, ok := service.MetaData["monthly_cost"].(float64); ok {
+		fmt.Fprintf(&b, "- monthly_cost: %.2f USD\n", cost)
+	}
+	fmt.Fprintf(&b, "- resource_count: %d\n", len(service.Resources))
+
+	resources := append([]ResourceMetadata{}, service.Resources...)
+	sort.SliceStable(resources, func(i, j int) bool {
+		return resources[i].ResourceID < resources[j].ResourceID
+	})
+	for _, resource := range resources {
+		fmt.Fprintf(&b, "\n## %s\n\n", resource.ResourceID)
+
+		var metadata map[string]interface{}
+		if err := decodeMetadata(resource.MetaData, &metadata); err != nil {
+			continue
+		}
+		for _, key := range sortedKeys(metadata) {
+			switch value := metadata[key].(type) {
+			case float64:
+				fmt.Fprintf(&b, "- %s: %s\n", key, formatMarkdownNumber(value))
+			case string, bool:
+				fmt.Fprintf(&b, "- %s: %v\n", key, value)
+			case []interface{}:
+				var items []string
+				for _, item := range value {
+					if s, ok := item.(string); ok {
+						items = append(items, s)
+					}
+				}
+				if len(items) > 0 && len(items) == len(value) {
+					fmt.Fprintf(&b, "- %s: %s\n", key, strings.Join(items, ", "))
+				}
+			}
+		}
+		if tags := resourceTags(resource.MetaData); len(tags) > 0 {
+			var pairs []string
+			for _, key := range sortedKeys(tags) {
+				pairs = append(pairs, key+"="+tags[key])
+			}
+			fmt.Fprintf(&b, "- tags: %s\n", strings.Join(pairs, ", "))
+		}
+	}
+	return b.String()
+}
+
+// formatMarkdownNumber prints whole numbers without an exponent, JSON decodes counts and sizes as float64 and
+// %v would print 1000000 as 1e+06
+func formatMarkdownNumber(value float64) string {
+	if value == math.Trunc(value) && math.Abs(value) < 1e15 {
+		return fmt.Sprintf("%.0f", value)
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+// openWebUIError is a response of Open WebUI with a status other than 200 OK
+type openWebUIError struct {
+	Status     string
+	StatusCode int
+	Detail     string
+}
+
+func (e *openWebUIError) Error() string {
+	if e.Detail == "" {
+		return fmt.Sprintf("received non-OK HTTP status: %s", e.Status)
+	}
+	return fmt.Sprintf("received non-OK HTTP status: %s: %s", e.Status, e.Detail)
+}
+
+// isDuplicateContentError reports whether Open WebUI rejected adding a document because the collection already
+// has one with the same content
+func isDuplicateContentError(err error) bool {
+	var webUIErr *openWebUIError
+	return errors.As(err, &webUIErr) && webUIErr.StatusCode == http.StatusBadRequest &&
+		strings.Contains(strings.ToLower(webUIErr.Detail), "duplicate content")
+}
+
+// openWebUIRequest sends a JSON request with the bearer token and decodes the response into target when given
+func openWebUIRequest(method, url, token string, body, target interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		jsonData, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
+		reader = bytes.NewBuffer(jsonData)
+	}
+
+	req, err := http.NewRequest(method, url, reader)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return sendOpenWebUIRequest(req, token, target)
+}
+
+// uploadOpenWebUIFile uploads a Markdown document as a multipart form
+func uploadOpenWebUIFile(server, token, fileName, content string, target interface{}) error {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("file", fileName)
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(part, content); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", server+"/api/v1/files/", &body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return sendOpenWebUIRequest(req, token, target)
+}
+
+func sendOpenWebUIRequest(req *http.Request, token string, target interface{}) error {
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		// Errors carry the reason in detail, e.g. {"detail": "Duplicate content detected. ..."}
+		var body struct {
+			Detail string `json:"detail"`
+		}
+		json.NewDecoder(resp.Body).Decode(&body)
+		return &openWebUIError{Status: resp.Status, StatusCode: resp.StatusCode, Detail: body.Detail}
+	}
+	if target == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(target); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package entity
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSaveInventoryKnowledge(t *testing.T) {
+	services := []ServiceMetadata{
+		{ServiceName: "Amazon Relational Database Service",
+			MetaData: map[string]interface{}{"region": "eu-west-1", "account_id": "123", "monthly_cost": 42.5},
+			Resources: []ResourceMetadata{
+				{ResourceID: "orders", MetaData: map[string]interface{}{
+					"engine": "postgres", "instance_type": "db.r6g.large", "allocated_storage": 1000000,
+					"tags":                  map[string]string{"team": "checkout"},
+					"cloudwatch_dimensions": map[string]string{"DBInstanceIdentifier": "orders"},
+				}},
+			}},
+	}
+	in := filepath.Join(t.TempDir(), "inventory.json")
+	assert.NoError(t, writeToJsonFile(services, in))
+
+	var calls []string
+	var uploaded string
+	uploadStatus := http.StatusOK
+	addStatus, addResponse := http.StatusOK, `{}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		calls = append(calls, r.Method+" "+r.URL.Path)
+		switch r.URL.Path {
+		case "/api/v1/knowledge/":
+			w.Write([]byte(`[{"id":"k1","name":"Inventory"}]`))
+		case "/api/v1/knowledge/k1":
+			w.Write([]byte(`{"id":"k1","name":"Inventory","files":[
+				{"id":"old","meta":{"name":"inventory-amazon-relational-database-service-eu-west-1.md"}},
+				{"id":"runbook","meta":{"name":"runbook.md"}}]}`))
+		case "/api/v1/files/":
+			if uploadStatus != http.StatusOK {
+				w.WriteHeader(uploadStatus)
+				return
+			}
+			file, _, err := r.FormFile("file")
+			assert.NoError(t, err)
+			content, _ := io.ReadAll(file)
+			uploaded = string(content)
+			json.NewEncoder(w).Encode(map[string]interface{}{"id": "new"})
+		case "/api/v1/knowledge/k1/file/add":
+			w.WriteHeader(addStatus)
+			w.Write([]byte(addResponse))
+		default:
+			w.Write([]byte(`{}`))
+		}
+	}))
+	defer server.Close()
+
+	assert.NoError(t, saveInventoryKnowledge(server.URL, "token", in, "Inventory"))
+	assert.Equal(t, []string{
+		"GET /api/v1/knowledge/",
+		"GET /api/v1/knowledge/k1",
+		"POST /api/v1/files/",
+		"POST /api/v1/knowledge/k1/file/add",
+		"POST /api/v1/knowledge/k1/file/remove",
+	}, calls)
+	assert.Equal(t, "# Amazon Relational Database Service\n\n"+
+		"- account_id: 123\n- region: eu-west-1\n- monthly_cost: 42.50 USD\n- resource_count: 1\n\n"+
+		"## orders\n\n- allocated_storage: 1000000\n- engine: postgres\n- instance_type: db.r6g.large\n- tags: team=checkout\n", uploaded)
+
+	// A failed upload keeps the previous document
+	calls = nil
+	uploadStatus = http.StatusInternalServerError
+	assert.EqualError(t, saveInventoryKnowledge(server.URL, "token", in, "Inventory"), "1 documents failed to upload or remove")
+	assert.Equal(t, []string{
+		"GET /api/v1/knowledge/",
+		"GET /api/v1/knowledge/k1",
+		"POST /api/v1/files/",
+	}, calls)
+
+	// An unchanged document keeps the previous one
+	uploadStatus = http.StatusOK
+	addStatus, addResponse = http.StatusBadRequest, `{"detail":"Duplicate content detected. Please provide unique content to proceed."}`
+	assert.NoError(t, saveInventoryKnowledge(server.URL, "token", in, "Inventory"))
+
+	// A rejected add is a failure
+	calls = nil
+	addStatus, addResponse = http.StatusForbidden, `{"detail":"You do not have permission to access this resource."}`
+	assert.EqualError(t, saveInventoryKnowledge(server.URL, "token", in, "Inventory"), "1 documents failed to upload or remove")
+	assert.Equal(t, []string{
+		"GET /api/v1/knowledge/",
+		"GET /api/v1/knowledge/k1",
+		"POST /api/v1/files/",
+		"POST /api/v1/knowledge/k1/file/add",
+		"DELETE /api/v1/files/new",
+	}, calls)
+}